@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// datasetFlagValue implements flag.Value so -dataset can be repeated on
+// the command line, each occurrence adding one path or glob pattern to
+// the corpus these benchmarks load.
+type datasetFlagValue struct {
+	patterns []string
+}
+
+func (d *datasetFlagValue) String() string {
+	return strings.Join(d.patterns, ",")
+}
+
+func (d *datasetFlagValue) Set(value string) error {
+	d.patterns = append(d.patterns, value)
+	return nil
+}
+
+var datasetFlags datasetFlagValue
+
+func init() {
+	flag.Var(&datasetFlags, "dataset", "path, directory, or glob pattern of .bin fixtures to benchmark (repeatable; defaults to ../data/messages if unset)")
+}
+
+// datasetPaths resolves every -dataset flag plus any bare paths passed
+// as trailing test binary arguments into a flat, deduplicated list of
+// fixture files, falling back to the default corpus directory if
+// neither was given. A matched directory contributes only its .bin
+// files; a matched non-directory path is listed whatever its extension.
+func datasetPaths() ([]string, error) {
+	patterns := append([]string{}, datasetFlags.patterns...)
+	patterns = append(patterns, flag.Args()...)
+	if len(patterns) == 0 {
+		patterns = []string{"../data/messages"}
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	addPath := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("dataset pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			// Not a glob, or it matched nothing; fall back to treating
+			// it as a literal path so a plain file or directory works.
+			matches = []string{pattern}
+		}
+
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				continue
+			}
+			if !info.IsDir() {
+				addPath(m)
+				continue
+			}
+			entries, err := os.ReadDir(m)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if strings.HasSuffix(e.Name(), ".bin") {
+					addPath(filepath.Join(m, e.Name()))
+				}
+			}
+		}
+	}
+	return paths, nil
+}
+
+// fixtureKey turns a path returned by datasetPaths into a benchmark/map
+// key that keeps enough of the path to stay unique across -dataset
+// roots: vendor interop dumps and the default corpus both happen to use
+// names like "session-establishment.bin", and keying by basename alone
+// would let one silently overwrite the other's entry.
+func fixtureKey(binPath string) string {
+	key := strings.TrimSuffix(binPath, ".bin")
+	key = strings.ReplaceAll(key, string(filepath.Separator), "__")
+	return strings.TrimPrefix(key, "..__")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/wmnsk/go-pfcp/message"
@@ -57,6 +58,38 @@ func BenchmarkGoUnmarshal(b *testing.B) {
 	}
 }
 
+// BenchmarkGoJSON times encoding/json.Marshal on the parsed messages. It
+// only encodes: nothing is decoded back or compared. The Rust harnesses
+// (benches/pfcp_bench.rs, benchmarks/rust) have no JSON benchmark to pair
+// it with; rs-pfcp's JSON form is checked for byte equality, untimed, by
+// tests/json_roundtrip.rs.
+func BenchmarkGoJSON(b *testing.B) {
+	testData, err := loadTestDataForParsing()
+	if err != nil {
+		b.Fatalf("Failed to load test data: %v", err)
+	}
+
+	parsedMessages := make(map[string]message.Message)
+	for name, data := range testData {
+		msg, err := message.Parse(data)
+		if err != nil {
+			continue
+		}
+		parsedMessages[name] = msg
+	}
+
+	for name, msg := range parsedMessages {
+		b.Run(name, func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 // Roundtrip benchmarks (marshal + unmarshal)
 func BenchmarkGoRoundtrip(b *testing.B) {
 	testData, err := loadTestDataForParsing()
@@ -2,7 +2,6 @@ package main
 
 import (
 	"io/ioutil"
-	"path/filepath"
 	"strings"
 	"testing"
 
@@ -27,23 +26,24 @@ func BenchmarkParseOnly(b *testing.B) {
 	}
 }
 
+// loadTestDataForParsing is loadTestData plus a parse check, so a
+// fixture that doesn't parse with this build of go-pfcp is silently
+// excluded rather than failing every benchmark that loads it. Results
+// are keyed by fixtureKey(binPath) rather than bare basename, so two
+// -dataset roots with a same-named fixture don't clobber each other.
 func loadTestDataForParsing() (map[string][]byte, error) {
 	messages := make(map[string][]byte)
-	dataDir := "../data/messages"
 
-	files, err := ioutil.ReadDir(dataDir)
+	paths, err := datasetPaths()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".bin") {
+	for _, binPath := range paths {
+		if !strings.HasSuffix(binPath, ".bin") {
 			continue
 		}
 
-		name := strings.TrimSuffix(file.Name(), ".bin")
-		binPath := filepath.Join(dataDir, file.Name())
-
 		data, err := ioutil.ReadFile(binPath)
 		if err != nil {
 			continue // Skip files we can't read
@@ -51,7 +51,7 @@ func loadTestDataForParsing() (map[string][]byte, error) {
 
 		// Try to parse first to validate
 		if _, err := message.Parse(data); err == nil {
-			messages[name] = data
+			messages[fixtureKey(binPath)] = data
 		}
 		// If parsing fails, skip this message
 	}
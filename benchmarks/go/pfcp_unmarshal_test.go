@@ -2,36 +2,36 @@ package main
 
 import (
 	"io/ioutil"
-	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/wmnsk/go-pfcp/message"
 )
 
+// loadTestData reads every fixture datasetPaths resolves (the -dataset
+// flag(s) and any trailing args, or ../data/messages by default) keyed
+// by fixtureKey(binPath), which keeps its source directory in the key so
+// two -dataset roots with a same-named fixture don't clobber each
+// other's entry.
 func loadTestData() (map[string][]byte, error) {
 	testData := make(map[string][]byte)
-	dataDir := "../data/messages"
 
-	files, err := ioutil.ReadDir(dataDir)
+	paths, err := datasetPaths()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".bin") {
+	for _, binPath := range paths {
+		if !strings.HasSuffix(binPath, ".bin") {
 			continue
 		}
 
-		name := strings.TrimSuffix(file.Name(), ".bin")
-		binPath := filepath.Join(dataDir, file.Name())
-
 		data, err := ioutil.ReadFile(binPath)
 		if err != nil {
 			continue // Skip files we can't read
 		}
 
-		testData[name] = data
+		testData[fixtureKey(binPath)] = data
 	}
 
 	return testData, nil
@@ -0,0 +1,46 @@
+// Package datapath defines the fastpath boundary the interop server's
+// PFCP handlers drive, modeled on omec-project/upf's fastPath interface:
+// Create/Update/Delete map 1:1 onto the grouped Create/Update/Remove
+// PDR/FAR/QER/URR IEs a Session Establishment/Modification/Deletion
+// Request carries, and PollUsage surfaces the accounting state a URR's
+// usage reporting triggers (volume threshold, time threshold, periodic)
+// are actually measured against.
+package datapath
+
+import "github.com/wmnsk/go-pfcp/ie"
+
+// UsageReport is the accounting data a Datapath hands back for a URR
+// whose reporting trigger has fired.
+type UsageReport struct {
+	URRID       uint32
+	Trigger     string
+	TotalVolume uint64
+}
+
+// Datapath is the fastpath boundary for one PFCP node's sessions. Every
+// method after the SEID takes the grouped IE exactly as it arrived in
+// the PFCP message (CreatePDR, UpdateFAR, ...), leaving field extraction
+// to the implementation.
+type Datapath interface {
+	AddPDR(seid uint64, pdr *ie.IE) error
+	AddFAR(seid uint64, far *ie.IE) error
+	AddQER(seid uint64, qer *ie.IE) error
+	AddURR(seid uint64, urr *ie.IE) error
+
+	UpdatePDR(seid uint64, pdr *ie.IE) error
+	UpdateFAR(seid uint64, far *ie.IE) error
+	UpdateQER(seid uint64, qer *ie.IE) error
+	UpdateURR(seid uint64, urr *ie.IE) error
+
+	DeletePDR(seid uint64, pdrID uint16) error
+	DeleteFAR(seid uint64, farID uint32) error
+	DeleteQER(seid uint64, qerID uint32) error
+	DeleteURR(seid uint64, urrID uint32) error
+
+	// SessionDelete tears down every rule installed for seid.
+	SessionDelete(seid uint64) error
+
+	// PollUsage returns the usage reports, if any, for URRs attached to
+	// seid whose reporting trigger has fired since the last poll.
+	PollUsage(seid uint64) ([]UsageReport, error)
+}
@@ -0,0 +1,126 @@
+package datapath
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/wmnsk/go-pfcp/ie"
+)
+
+// LoggingDatapath wraps a Datapath and emits one line of structured JSON
+// per operation to w before delegating to next, so interop runs can be
+// replayed or diffed without instrumenting the caller. PollUsage is the
+// exception: it delegates first and logs one line per usage report
+// returned, nothing when there are none.
+type LoggingDatapath struct {
+	next Datapath
+	w    io.Writer
+}
+
+// NewLoggingDatapath returns a Datapath that logs every call to w and
+// then forwards it to next (PollUsage: forwards, then logs its reports).
+func NewLoggingDatapath(next Datapath, w io.Writer) *LoggingDatapath {
+	return &LoggingDatapath{next: next, w: w}
+}
+
+type logEntry struct {
+	Time string `json:"time"`
+	Op   string `json:"op"`
+	SEID uint64 `json:"seid"`
+	ID   uint32 `json:"id,omitempty"`
+}
+
+func (d *LoggingDatapath) log(op string, seid uint64, id uint32) {
+	b, err := json.Marshal(logEntry{
+		Time: time.Now().UTC().Format(time.RFC3339Nano),
+		Op:   op,
+		SEID: seid,
+		ID:   id,
+	})
+	if err != nil {
+		return
+	}
+	d.w.Write(append(b, '\n'))
+}
+
+func (d *LoggingDatapath) AddPDR(seid uint64, pdr *ie.IE) error {
+	id, _ := pdr.PDRID()
+	d.log("add_pdr", seid, uint32(id))
+	return d.next.AddPDR(seid, pdr)
+}
+
+func (d *LoggingDatapath) AddFAR(seid uint64, far *ie.IE) error {
+	id, _ := far.FARID()
+	d.log("add_far", seid, id)
+	return d.next.AddFAR(seid, far)
+}
+
+func (d *LoggingDatapath) AddQER(seid uint64, qer *ie.IE) error {
+	id, _ := qer.QERID()
+	d.log("add_qer", seid, id)
+	return d.next.AddQER(seid, qer)
+}
+
+func (d *LoggingDatapath) AddURR(seid uint64, urr *ie.IE) error {
+	id, _ := urr.URRID()
+	d.log("add_urr", seid, id)
+	return d.next.AddURR(seid, urr)
+}
+
+func (d *LoggingDatapath) UpdatePDR(seid uint64, pdr *ie.IE) error {
+	id, _ := pdr.PDRID()
+	d.log("update_pdr", seid, uint32(id))
+	return d.next.UpdatePDR(seid, pdr)
+}
+
+func (d *LoggingDatapath) UpdateFAR(seid uint64, far *ie.IE) error {
+	id, _ := far.FARID()
+	d.log("update_far", seid, id)
+	return d.next.UpdateFAR(seid, far)
+}
+
+func (d *LoggingDatapath) UpdateQER(seid uint64, qer *ie.IE) error {
+	id, _ := qer.QERID()
+	d.log("update_qer", seid, id)
+	return d.next.UpdateQER(seid, qer)
+}
+
+func (d *LoggingDatapath) UpdateURR(seid uint64, urr *ie.IE) error {
+	id, _ := urr.URRID()
+	d.log("update_urr", seid, id)
+	return d.next.UpdateURR(seid, urr)
+}
+
+func (d *LoggingDatapath) DeletePDR(seid uint64, pdrID uint16) error {
+	d.log("delete_pdr", seid, uint32(pdrID))
+	return d.next.DeletePDR(seid, pdrID)
+}
+
+func (d *LoggingDatapath) DeleteFAR(seid uint64, farID uint32) error {
+	d.log("delete_far", seid, farID)
+	return d.next.DeleteFAR(seid, farID)
+}
+
+func (d *LoggingDatapath) DeleteQER(seid uint64, qerID uint32) error {
+	d.log("delete_qer", seid, qerID)
+	return d.next.DeleteQER(seid, qerID)
+}
+
+func (d *LoggingDatapath) DeleteURR(seid uint64, urrID uint32) error {
+	d.log("delete_urr", seid, urrID)
+	return d.next.DeleteURR(seid, urrID)
+}
+
+func (d *LoggingDatapath) SessionDelete(seid uint64) error {
+	d.log("session_delete", seid, 0)
+	return d.next.SessionDelete(seid)
+}
+
+func (d *LoggingDatapath) PollUsage(seid uint64) ([]UsageReport, error) {
+	reports, err := d.next.PollUsage(seid)
+	for _, r := range reports {
+		d.log("usage_report:"+r.Trigger, seid, r.URRID)
+	}
+	return reports, err
+}
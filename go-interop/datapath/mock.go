@@ -0,0 +1,173 @@
+package datapath
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/wmnsk/go-pfcp/ie"
+)
+
+type urrState struct {
+	rule     *ie.IE
+	reported bool
+}
+
+type sessionState struct {
+	pdrs map[uint16]*ie.IE
+	fars map[uint32]*ie.IE
+	qers map[uint32]*ie.IE
+	urrs map[uint32]*urrState
+}
+
+func newSessionState() *sessionState {
+	return &sessionState{
+		pdrs: make(map[uint16]*ie.IE),
+		fars: make(map[uint32]*ie.IE),
+		qers: make(map[uint32]*ie.IE),
+		urrs: make(map[uint32]*urrState),
+	}
+}
+
+// MockDatapath is an in-memory Datapath. It is the default backend for
+// the interop server and is meant to be queried directly by tests, since
+// it stores rules rather than forwarding packets.
+type MockDatapath struct {
+	mu       sync.Mutex
+	sessions map[uint64]*sessionState
+}
+
+// NewMockDatapath returns an empty MockDatapath.
+func NewMockDatapath() *MockDatapath {
+	return &MockDatapath{sessions: make(map[uint64]*sessionState)}
+}
+
+func (d *MockDatapath) session(seid uint64) *sessionState {
+	s, ok := d.sessions[seid]
+	if !ok {
+		s = newSessionState()
+		d.sessions[seid] = s
+	}
+	return s
+}
+
+// PDRs returns the Create/Update PDR IEs currently installed for seid,
+// keyed by PDR ID. Intended for use by tests.
+func (d *MockDatapath) PDRs(seid uint64) map[uint16]*ie.IE {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s, ok := d.sessions[seid]; ok {
+		return s.pdrs
+	}
+	return nil
+}
+
+func (d *MockDatapath) AddPDR(seid uint64, pdr *ie.IE) error {
+	id, err := pdr.PDRID()
+	if err != nil {
+		return fmt.Errorf("datapath: AddPDR: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session(seid).pdrs[id] = pdr
+	return nil
+}
+
+func (d *MockDatapath) AddFAR(seid uint64, far *ie.IE) error {
+	id, err := far.FARID()
+	if err != nil {
+		return fmt.Errorf("datapath: AddFAR: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session(seid).fars[id] = far
+	return nil
+}
+
+func (d *MockDatapath) AddQER(seid uint64, qer *ie.IE) error {
+	id, err := qer.QERID()
+	if err != nil {
+		return fmt.Errorf("datapath: AddQER: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session(seid).qers[id] = qer
+	return nil
+}
+
+func (d *MockDatapath) AddURR(seid uint64, urr *ie.IE) error {
+	id, err := urr.URRID()
+	if err != nil {
+		return fmt.Errorf("datapath: AddURR: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session(seid).urrs[id] = &urrState{rule: urr}
+	return nil
+}
+
+func (d *MockDatapath) UpdatePDR(seid uint64, pdr *ie.IE) error { return d.AddPDR(seid, pdr) }
+func (d *MockDatapath) UpdateFAR(seid uint64, far *ie.IE) error { return d.AddFAR(seid, far) }
+func (d *MockDatapath) UpdateQER(seid uint64, qer *ie.IE) error { return d.AddQER(seid, qer) }
+func (d *MockDatapath) UpdateURR(seid uint64, urr *ie.IE) error { return d.AddURR(seid, urr) }
+
+func (d *MockDatapath) DeletePDR(seid uint64, pdrID uint16) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.session(seid).pdrs, pdrID)
+	return nil
+}
+
+func (d *MockDatapath) DeleteFAR(seid uint64, farID uint32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.session(seid).fars, farID)
+	return nil
+}
+
+func (d *MockDatapath) DeleteQER(seid uint64, qerID uint32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.session(seid).qers, qerID)
+	return nil
+}
+
+func (d *MockDatapath) DeleteURR(seid uint64, urrID uint32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.session(seid).urrs, urrID)
+	return nil
+}
+
+func (d *MockDatapath) SessionDelete(seid uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sessions, seid)
+	return nil
+}
+
+// PollUsage reports every URR attached to seid that hasn't already been
+// reported once. MockDatapath has no real traffic counters, so it trips
+// each URR's trigger exactly once, the moment it's first polled, which is
+// enough to exercise the Session Report Request path end to end.
+func (d *MockDatapath) PollUsage(seid uint64) ([]UsageReport, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.sessions[seid]
+	if !ok {
+		return nil, nil
+	}
+
+	var reports []UsageReport
+	for id, u := range s.urrs {
+		if u.reported {
+			continue
+		}
+		u.reported = true
+		reports = append(reports, UsageReport{
+			URRID:   id,
+			Trigger: "volume_threshold",
+		})
+	}
+	return reports, nil
+}
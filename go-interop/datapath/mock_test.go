@@ -0,0 +1,119 @@
+package datapath
+
+import (
+	"testing"
+
+	"github.com/wmnsk/go-pfcp/ie"
+)
+
+func TestMockDatapathAddAndQueryPDR(t *testing.T) {
+	d := NewMockDatapath()
+	pdr := ie.NewCreatePDR(ie.NewPDRID(1))
+
+	if err := d.AddPDR(42, pdr); err != nil {
+		t.Fatalf("AddPDR: %v", err)
+	}
+
+	pdrs := d.PDRs(42)
+	if len(pdrs) != 1 {
+		t.Fatalf("PDRs(42) = %d entries, want 1", len(pdrs))
+	}
+	if _, ok := pdrs[1]; !ok {
+		t.Fatalf("PDRs(42) missing PDR ID 1: %v", pdrs)
+	}
+}
+
+func TestMockDatapathUpdatePDROverwrites(t *testing.T) {
+	d := NewMockDatapath()
+	first := ie.NewCreatePDR(ie.NewPDRID(1), ie.NewPrecedence(100))
+	second := ie.NewCreatePDR(ie.NewPDRID(1), ie.NewPrecedence(200))
+
+	if err := d.AddPDR(1, first); err != nil {
+		t.Fatalf("AddPDR: %v", err)
+	}
+	if err := d.UpdatePDR(1, second); err != nil {
+		t.Fatalf("UpdatePDR: %v", err)
+	}
+
+	pdrs := d.PDRs(1)
+	if len(pdrs) != 1 {
+		t.Fatalf("PDRs(1) = %d entries, want 1 (update should overwrite, not add)", len(pdrs))
+	}
+	if pdrs[1] != second {
+		t.Fatalf("PDRs(1)[1] wasn't replaced by the updated rule")
+	}
+}
+
+func TestMockDatapathDeletePDR(t *testing.T) {
+	d := NewMockDatapath()
+	if err := d.AddPDR(1, ie.NewCreatePDR(ie.NewPDRID(5))); err != nil {
+		t.Fatalf("AddPDR: %v", err)
+	}
+	if err := d.DeletePDR(1, 5); err != nil {
+		t.Fatalf("DeletePDR: %v", err)
+	}
+	if pdrs := d.PDRs(1); len(pdrs) != 0 {
+		t.Fatalf("PDRs(1) = %v, want empty after DeletePDR", pdrs)
+	}
+}
+
+func TestMockDatapathAddPDRWithoutPDRIDErrors(t *testing.T) {
+	d := NewMockDatapath()
+	// A grouped IE with no PDRID child: AddPDR must surface the extraction
+	// error rather than install a zero-value rule.
+	if err := d.AddPDR(1, ie.NewCreatePDR(ie.NewPrecedence(100))); err == nil {
+		t.Fatal("AddPDR with no PDRID child: got nil error, want one")
+	}
+}
+
+func TestMockDatapathSessionDeleteRemovesAllRules(t *testing.T) {
+	d := NewMockDatapath()
+	if err := d.AddPDR(1, ie.NewCreatePDR(ie.NewPDRID(1))); err != nil {
+		t.Fatalf("AddPDR: %v", err)
+	}
+	if err := d.AddFAR(1, ie.NewCreateFAR(ie.NewFARID(1))); err != nil {
+		t.Fatalf("AddFAR: %v", err)
+	}
+
+	if err := d.SessionDelete(1); err != nil {
+		t.Fatalf("SessionDelete: %v", err)
+	}
+
+	if pdrs := d.PDRs(1); pdrs != nil {
+		t.Fatalf("PDRs(1) after SessionDelete = %v, want nil (session gone entirely)", pdrs)
+	}
+}
+
+func TestMockDatapathPollUsageFiresOncePerURR(t *testing.T) {
+	d := NewMockDatapath()
+	if err := d.AddURR(1, ie.NewCreateURR(ie.NewURRID(7))); err != nil {
+		t.Fatalf("AddURR: %v", err)
+	}
+
+	reports, err := d.PollUsage(1)
+	if err != nil {
+		t.Fatalf("PollUsage: %v", err)
+	}
+	if len(reports) != 1 || reports[0].URRID != 7 {
+		t.Fatalf("PollUsage first call = %+v, want one report for URR 7", reports)
+	}
+
+	reports, err = d.PollUsage(1)
+	if err != nil {
+		t.Fatalf("PollUsage: %v", err)
+	}
+	if len(reports) != 0 {
+		t.Fatalf("PollUsage second call = %+v, want no reports (already fired once)", reports)
+	}
+}
+
+func TestMockDatapathPollUsageUnknownSession(t *testing.T) {
+	d := NewMockDatapath()
+	reports, err := d.PollUsage(999)
+	if err != nil {
+		t.Fatalf("PollUsage on unknown session: %v", err)
+	}
+	if reports != nil {
+		t.Fatalf("PollUsage on unknown session = %v, want nil", reports)
+	}
+}
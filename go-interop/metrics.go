@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics mirrors the observability approach used in omec-project/upf's
+// pfcpiface: counters keyed by message type and cause, a gauge tracking
+// live sessions, and per-message-type request/response latency.
+var (
+	messagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pfcp_messages_total",
+		Help: "Total PFCP messages processed, by message type, direction and cause.",
+	}, []string{"message_type", "direction", "cause"})
+
+	sessionsActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pfcp_sessions_active",
+		Help: "Number of PFCP sessions currently tracked by the server.",
+	})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pfcp_request_duration_seconds",
+		Help:    "Time from a handler receiving a request to it returning the response, by message type; the response write is not included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"message_type"})
+
+	reportRequestsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pfcp_session_report_requests_sent_total",
+		Help: "Session Report Requests sent to peers, by trigger.",
+	}, []string{"trigger"})
+
+	reportResponsesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pfcp_session_report_responses_received_total",
+		Help: "Session Report Responses received from peers.",
+	})
+)
+
+const (
+	directionReceived = "received"
+	directionSent     = "sent"
+
+	causeAccepted   = "accepted"
+	causeParseError = "parse_error"
+)
+
+// observeRequest records the duration from start to now, labeled by
+// message type. Handlers defer it, so it ends when the handler returns
+// its response, before that response is written to the peer.
+func observeRequest(messageType string, start time.Time) {
+	requestDuration.WithLabelValues(messageType).Observe(time.Since(start).Seconds())
+}
+
+// serveMetrics starts a Prometheus /metrics endpoint on addr and blocks
+// until the server exits. Callers run it in its own goroutine.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	log.Printf("Metrics listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Metrics server exited: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+// Package pfcpchan provides a pluggable abstraction for sending and
+// receiving PFCP messages over an underlying transport, modeled on the 9P
+// Channel pattern so the transport (UDP, a TLS/DTLS packet conn, an
+// in-memory mock for tests) can be swapped without touching message
+// dispatch code.
+package pfcpchan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/wmnsk/go-pfcp/message"
+)
+
+// defaultMaxSize is the receive buffer size used until SetMaxSize is
+// called, large enough for the PFCP messages this package has seen in
+// practice without relying on IP fragmentation.
+const defaultMaxSize = 1500
+
+// Channel reads and writes PFCP messages over some underlying transport.
+// Implementations must respect ctx cancellation and ctx.Deadline() for
+// both ReadMessage and WriteMessage.
+type Channel interface {
+	// ReadMessage blocks until a PFCP message arrives, ctx is done, or an
+	// error occurs. On success *out is replaced with the parsed message
+	// and the sender's address is returned.
+	ReadMessage(ctx context.Context, out *message.Message) (net.Addr, error)
+
+	// WriteMessage marshals m and sends it to addr.
+	WriteMessage(ctx context.Context, addr net.Addr, m message.Message) error
+
+	// SetMaxSize bounds the size of the receive buffer used by
+	// ReadMessage.
+	SetMaxSize(n int)
+}
+
+// UDPChannel is a Channel backed by a *net.UDPConn.
+type UDPChannel struct {
+	conn *net.UDPConn
+	buf  []byte
+}
+
+// NewUDPChannel returns a Channel that reads and writes PFCP messages on
+// conn. conn may be connected (client) or unconnected (server); in the
+// connected case callers may pass a nil addr to WriteMessage.
+func NewUDPChannel(conn *net.UDPConn) *UDPChannel {
+	return &UDPChannel{
+		conn: conn,
+		buf:  make([]byte, defaultMaxSize),
+	}
+}
+
+// SetMaxSize implements Channel.
+func (c *UDPChannel) SetMaxSize(n int) {
+	c.buf = make([]byte, n)
+}
+
+// ReadMessage implements Channel.
+func (c *UDPChannel) ReadMessage(ctx context.Context, out *message.Message) (net.Addr, error) {
+	if err := c.applyDeadline(ctx, c.conn.SetReadDeadline); err != nil {
+		return nil, err
+	}
+	defer c.watchCancel(ctx)()
+
+	n, addr, err := c.conn.ReadFromUDP(c.buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	msg, err := message.Parse(c.buf[:n])
+	if err != nil {
+		return addr, fmt.Errorf("pfcpchan: parse message from %s: %w", addr, err)
+	}
+	*out = msg
+	return addr, nil
+}
+
+// WriteMessage implements Channel.
+func (c *UDPChannel) WriteMessage(ctx context.Context, addr net.Addr, m message.Message) error {
+	if err := c.applyDeadline(ctx, c.conn.SetWriteDeadline); err != nil {
+		return err
+	}
+	defer c.watchCancel(ctx)()
+
+	data := make([]byte, m.MarshalLen())
+	if err := m.MarshalTo(data); err != nil {
+		return fmt.Errorf("pfcpchan: marshal %s: %w", m.MessageTypeName(), err)
+	}
+
+	var err error
+	if addr == nil {
+		_, err = c.conn.Write(data)
+	} else {
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			return fmt.Errorf("pfcpchan: UDPChannel requires a *net.UDPAddr, got %T", addr)
+		}
+		_, err = c.conn.WriteToUDP(data, udpAddr)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("pfcpchan: write %s: %w", m.MessageTypeName(), err)
+	}
+	return nil
+}
+
+func (c *UDPChannel) applyDeadline(ctx context.Context, set func(time.Time) error) error {
+	if dl, ok := ctx.Deadline(); ok {
+		return set(dl)
+	}
+	return set(time.Time{})
+}
+
+// watchCancel arranges for a plain context.WithCancel with no deadline
+// of its own to still unblock the conn: applyDeadline only ever sets a
+// real deadline when ctx.Deadline() returns one, so a bare cancel would
+// otherwise never be observed by a blocked ReadFromUDP/WriteToUDP. It
+// is called before the blocking call and returns a cleanup func that
+// the caller defers, so the watcher goroutine exits once the calling
+// method returns, deadline or not.
+func (c *UDPChannel) watchCancel(ctx context.Context) func() {
+	if _, ok := ctx.Deadline(); ok || ctx.Done() == nil {
+		// Deadline already covers this case, or ctx can never be
+		// canceled (context.Background/TODO) - nothing to watch.
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// Force the blocked read/write to return immediately;
+			// ReadMessage/WriteMessage turn the resulting error into
+			// ctx.Err() once they see it.
+			_ = c.conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
@@ -0,0 +1,127 @@
+package pfcpchan
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/wmnsk/go-pfcp/message"
+)
+
+// Handler dispatches incoming PFCP messages by type. One method per
+// message type used by the interop examples; embed UnimplementedHandler
+// to get no-op defaults for the ones a particular program doesn't care
+// about.
+//
+// This is the plain dispatch path: a response is written as soon as a
+// handler returns it, with no retransmission or duplicate-request
+// handling. simple-server uses it as-is. Programs that need request/
+// response correlation across retries - currently session-server and
+// session-client - use pfcptx.Transactor instead, which layers that on
+// top of a Channel via its own HandlerFunc rather than this interface.
+type Handler interface {
+	HandleAssociationSetupRequest(ctx context.Context, ch Channel, from net.Addr, m *message.AssociationSetupRequest) error
+	HandleSessionEstablishmentRequest(ctx context.Context, ch Channel, from net.Addr, m *message.SessionEstablishmentRequest) error
+	HandleSessionModificationRequest(ctx context.Context, ch Channel, from net.Addr, m *message.SessionModificationRequest) error
+	HandleSessionDeletionRequest(ctx context.Context, ch Channel, from net.Addr, m *message.SessionDeletionRequest) error
+	HandleSessionReportRequest(ctx context.Context, ch Channel, from net.Addr, m *message.SessionReportRequest) error
+	HandleSessionReportResponse(ctx context.Context, ch Channel, from net.Addr, m *message.SessionReportResponse) error
+}
+
+// UnimplementedHandler provides no-op implementations of every Handler
+// method. Embed it in a concrete handler and override only the message
+// types that handler needs to act on.
+type UnimplementedHandler struct{}
+
+func (UnimplementedHandler) HandleAssociationSetupRequest(context.Context, Channel, net.Addr, *message.AssociationSetupRequest) error {
+	return nil
+}
+
+func (UnimplementedHandler) HandleSessionEstablishmentRequest(context.Context, Channel, net.Addr, *message.SessionEstablishmentRequest) error {
+	return nil
+}
+
+func (UnimplementedHandler) HandleSessionModificationRequest(context.Context, Channel, net.Addr, *message.SessionModificationRequest) error {
+	return nil
+}
+
+func (UnimplementedHandler) HandleSessionDeletionRequest(context.Context, Channel, net.Addr, *message.SessionDeletionRequest) error {
+	return nil
+}
+
+func (UnimplementedHandler) HandleSessionReportRequest(context.Context, Channel, net.Addr, *message.SessionReportRequest) error {
+	return nil
+}
+
+func (UnimplementedHandler) HandleSessionReportResponse(context.Context, Channel, net.Addr, *message.SessionReportResponse) error {
+	return nil
+}
+
+// ServeOption configures Serve.
+type ServeOption func(*serveConfig)
+
+type serveConfig struct {
+	onParseError func(error)
+}
+
+// WithParseErrorHandler registers fn to be called whenever ReadMessage
+// fails to parse an inbound packet, e.g. so a caller can bump a
+// parse_error metric without Serve knowing about metrics at all.
+func WithParseErrorHandler(fn func(error)) ServeOption {
+	return func(c *serveConfig) { c.onParseError = fn }
+}
+
+// Serve reads messages from ch and dispatches each to the matching
+// Handler method until ctx is done or the transport itself fails.
+// Malformed packets are logged and skipped rather than ending the loop,
+// matching the behavior of the original inline server dispatch.
+func Serve(ctx context.Context, ch Channel, h Handler, opts ...ServeOption) error {
+	var cfg serveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var msg message.Message
+	for {
+		from, err := ch.ReadMessage(ctx, &msg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if from != nil {
+				// ReadMessage returns the sender's address alongside a
+				// parse error; the transport itself is fine, so log and
+				// keep serving.
+				log.Printf("pfcpchan: %v", err)
+				if cfg.onParseError != nil {
+					cfg.onParseError(err)
+				}
+				continue
+			}
+			return err
+		}
+
+		if err := dispatch(ctx, ch, h, from, msg); err != nil {
+			return err
+		}
+	}
+}
+
+func dispatch(ctx context.Context, ch Channel, h Handler, from net.Addr, msg message.Message) error {
+	switch m := msg.(type) {
+	case *message.AssociationSetupRequest:
+		return h.HandleAssociationSetupRequest(ctx, ch, from, m)
+	case *message.SessionEstablishmentRequest:
+		return h.HandleSessionEstablishmentRequest(ctx, ch, from, m)
+	case *message.SessionModificationRequest:
+		return h.HandleSessionModificationRequest(ctx, ch, from, m)
+	case *message.SessionDeletionRequest:
+		return h.HandleSessionDeletionRequest(ctx, ch, from, m)
+	case *message.SessionReportRequest:
+		return h.HandleSessionReportRequest(ctx, ch, from, m)
+	case *message.SessionReportResponse:
+		return h.HandleSessionReportResponse(ctx, ch, from, m)
+	default:
+		return nil
+	}
+}
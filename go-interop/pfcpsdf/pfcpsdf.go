@@ -0,0 +1,226 @@
+// Package pfcpsdf parses SDF (Service Data Flow) flow descriptions in the
+// 3GPP TS 29.212 clause 5.4.2 "IPFilterRule" grammar used throughout
+// omec-project/upf's parse-sdf.go, e.g.:
+//
+//	permit out ip from 10.0.0.0/8 to assigned 80-443
+//
+// and turns each line into the IEs the session client needs to build a
+// PDI: an SDF Filter carrying the original flow description text, a UE IP
+// Address when either endpoint is the literal "assigned", and (for named
+// protocols with an application identifier) an Application ID.
+package pfcpsdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wmnsk/go-pfcp/ie"
+)
+
+// Action is the permit/deny verdict of a rule.
+type Action string
+
+// Direction is the traffic direction a rule applies to, from the
+// perspective of the UE.
+type Direction string
+
+const (
+	ActionPermit Action = "permit"
+	ActionDeny   Action = "deny"
+
+	DirectionIn  Direction = "in"
+	DirectionOut Direction = "out"
+)
+
+// PortRange is an inclusive TCP/UDP port range. A zero value means "no
+// port restriction".
+type PortRange struct {
+	Low, High uint16
+}
+
+func (p PortRange) String() string {
+	if p == (PortRange{}) {
+		return ""
+	}
+	if p.Low == p.High {
+		return strconv.Itoa(int(p.Low))
+	}
+	return fmt.Sprintf("%d-%d", p.Low, p.High)
+}
+
+// Endpoint is one side ("from" or "to") of a flow description.
+type Endpoint struct {
+	// Assigned is true for the literal "assigned" keyword, meaning "the
+	// UE's own IP address", as opposed to an explicit CIDR.
+	Assigned bool
+	// CIDR is the address/prefix text (e.g. "10.0.0.0/8"), empty when
+	// Assigned is true or the endpoint was "any".
+	CIDR  string
+	Ports PortRange
+}
+
+// Rule is one parsed SDF flow-description line.
+type Rule struct {
+	Action    Action
+	Direction Direction
+	// Protocol is "ip", "tcp", "udp", "icmp", or a numeric protocol
+	// number, exactly as it appeared in the rule.
+	Protocol string
+	From     Endpoint
+	To       Endpoint
+
+	// raw is the original line, reused verbatim as the SDF Filter's flow
+	// description so the on-the-wire text matches what the operator wrote.
+	raw string
+}
+
+// ParseRule parses a single SDF flow-description line.
+func ParseRule(line string) (*Rule, error) {
+	raw := strings.TrimSpace(line)
+	fields := strings.Fields(raw)
+	if len(fields) < 6 {
+		return nil, fmt.Errorf("pfcpsdf: rule %q: expected at least 6 fields, got %d", raw, len(fields))
+	}
+
+	r := &Rule{raw: raw}
+
+	switch Action(fields[0]) {
+	case ActionPermit, ActionDeny:
+		r.Action = Action(fields[0])
+	default:
+		return nil, fmt.Errorf("pfcpsdf: rule %q: unknown action %q", raw, fields[0])
+	}
+
+	switch Direction(fields[1]) {
+	case DirectionIn, DirectionOut:
+		r.Direction = Direction(fields[1])
+	default:
+		return nil, fmt.Errorf("pfcpsdf: rule %q: unknown direction %q", raw, fields[1])
+	}
+
+	switch proto := fields[2]; proto {
+	case "ip", "tcp", "udp", "icmp":
+	default:
+		if _, err := strconv.ParseUint(proto, 10, 8); err != nil {
+			return nil, fmt.Errorf("pfcpsdf: rule %q: unknown protocol %q", raw, proto)
+		}
+	}
+	r.Protocol = fields[2]
+
+	if fields[3] != "from" {
+		return nil, fmt.Errorf("pfcpsdf: rule %q: expected \"from\", got %q", raw, fields[3])
+	}
+
+	rest := fields[4:]
+	from, rest, err := parseEndpoint(rest)
+	if err != nil {
+		return nil, fmt.Errorf("pfcpsdf: rule %q: %w", raw, err)
+	}
+	r.From = from
+
+	if len(rest) == 0 || rest[0] != "to" {
+		return nil, fmt.Errorf("pfcpsdf: rule %q: expected \"to\"", raw)
+	}
+	rest = rest[1:]
+
+	to, rest, err := parseEndpoint(rest)
+	if err != nil {
+		return nil, fmt.Errorf("pfcpsdf: rule %q: %w", raw, err)
+	}
+	r.To = to
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("pfcpsdf: rule %q: unexpected trailing tokens %v", raw, rest)
+	}
+
+	return r, nil
+}
+
+// parseEndpoint consumes the address token (and an optional port-range
+// token immediately following it) from fields, returning the remaining
+// unconsumed fields.
+func parseEndpoint(fields []string) (Endpoint, []string, error) {
+	if len(fields) == 0 {
+		return Endpoint{}, nil, fmt.Errorf("missing address")
+	}
+
+	var ep Endpoint
+	switch addr := fields[0]; addr {
+	case "assigned":
+		ep.Assigned = true
+	case "any":
+		// no address restriction
+	default:
+		ep.CIDR = addr
+	}
+	fields = fields[1:]
+
+	if len(fields) > 0 && fields[0] != "to" {
+		ports, err := parsePortRange(fields[0])
+		if err != nil {
+			return Endpoint{}, nil, err
+		}
+		ep.Ports = ports
+		fields = fields[1:]
+	}
+
+	return ep, fields, nil
+}
+
+func parsePortRange(s string) (PortRange, error) {
+	lo, hi, found := strings.Cut(s, "-")
+	low, err := strconv.ParseUint(lo, 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port %q: %w", s, err)
+	}
+	if !found {
+		return PortRange{Low: uint16(low), High: uint16(low)}, nil
+	}
+	high, err := strconv.ParseUint(hi, 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	return PortRange{Low: uint16(low), High: uint16(high)}, nil
+}
+
+// FlowDescription returns the rule in its original IPFilterRule text, the
+// form the SDF Filter IE carries on the wire.
+func (r *Rule) FlowDescription() string {
+	return r.raw
+}
+
+// applicationID returns the App ID r's protocol maps to, or "" if
+// Protocol is "ip" or a bare protocol number: only a named transport
+// protocol (tcp, udp, icmp) is specific enough to identify an
+// application, matching the set of protocol keywords ParseRule accepts
+// as something other than a raw number.
+func (r *Rule) applicationID() string {
+	switch r.Protocol {
+	case "tcp", "udp", "icmp":
+		return r.Protocol
+	default:
+		return ""
+	}
+}
+
+// BuildPDI packages the rule into the IEs needed for a Create PDR's PDI:
+// Source Interface, an SDF Filter carrying the flow description, a UE IP
+// Address when either endpoint used the "assigned" keyword, and an
+// Application ID when the protocol names one.
+func (r *Rule) BuildPDI(sourceInterface uint8, ueIPv4 string) *ie.IE {
+	pdiIEs := []*ie.IE{
+		ie.NewSourceInterface(sourceInterface),
+		ie.NewSDFFilter(r.FlowDescription(), "", "", "", 1),
+	}
+
+	if (r.From.Assigned || r.To.Assigned) && ueIPv4 != "" {
+		pdiIEs = append(pdiIEs, ie.NewUEIPAddress(0x02, ueIPv4, "", 0, 0)) // V4 flag
+	}
+
+	if appID := r.applicationID(); appID != "" {
+		pdiIEs = append(pdiIEs, ie.NewApplicationID(appID))
+	}
+
+	return ie.NewPDI(pdiIEs...)
+}
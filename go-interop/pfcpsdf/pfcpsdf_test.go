@@ -0,0 +1,135 @@
+package pfcpsdf
+
+import (
+	"testing"
+
+	"github.com/wmnsk/go-pfcp/ie"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    Rule
+		wantErr bool
+	}{
+		{
+			line: "permit out ip from 10.0.0.0/8 to assigned 80-443",
+			want: Rule{
+				Action:    ActionPermit,
+				Direction: DirectionOut,
+				Protocol:  "ip",
+				From:      Endpoint{CIDR: "10.0.0.0/8"},
+				To:        Endpoint{Assigned: true, Ports: PortRange{Low: 80, High: 443}},
+			},
+		},
+		{
+			line: "permit in tcp from assigned to any 443",
+			want: Rule{
+				Action:    ActionPermit,
+				Direction: DirectionIn,
+				Protocol:  "tcp",
+				From:      Endpoint{Assigned: true},
+				To:        Endpoint{Ports: PortRange{Low: 443, High: 443}},
+			},
+		},
+		{
+			line:    "deny out ip from",
+			wantErr: true,
+		},
+		{
+			line:    "maybe out ip from any to any",
+			wantErr: true,
+		},
+		{
+			line:    "permit sideways ip from any to any",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := ParseRule(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRule(%q): got nil error, want one", tt.line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.line, err)
+			}
+			if got.Action != tt.want.Action || got.Direction != tt.want.Direction || got.Protocol != tt.want.Protocol {
+				t.Fatalf("ParseRule(%q) = %+v, want %+v", tt.line, *got, tt.want)
+			}
+			if got.From != tt.want.From {
+				t.Fatalf("ParseRule(%q).From = %+v, want %+v", tt.line, got.From, tt.want.From)
+			}
+			if got.To != tt.want.To {
+				t.Fatalf("ParseRule(%q).To = %+v, want %+v", tt.line, got.To, tt.want.To)
+			}
+			if got.FlowDescription() != tt.line {
+				t.Fatalf("FlowDescription() = %q, want original line %q", got.FlowDescription(), tt.line)
+			}
+		})
+	}
+}
+
+func TestApplicationID(t *testing.T) {
+	tests := []struct {
+		protocol string
+		want     string
+	}{
+		{"ip", ""},
+		{"17", ""},
+		{"tcp", "tcp"},
+		{"udp", "udp"},
+		{"icmp", "icmp"},
+	}
+
+	for _, tt := range tests {
+		r := &Rule{Protocol: tt.protocol}
+		if got := r.applicationID(); got != tt.want {
+			t.Errorf("applicationID() for protocol %q = %q, want %q", tt.protocol, got, tt.want)
+		}
+	}
+}
+
+func TestBuildPDIIncludesApplicationIDForNamedProtocol(t *testing.T) {
+	r, err := ParseRule("permit out tcp from assigned to any 443")
+	if err != nil {
+		t.Fatalf("ParseRule: %v", err)
+	}
+
+	pdi := r.BuildPDI(0, "10.0.0.1")
+
+	var sawApplicationID, sawUEIPAddress bool
+	for _, child := range pdi.ChildIEs {
+		switch child.Type {
+		case ie.ApplicationID:
+			sawApplicationID = true
+		case ie.UEIPAddress:
+			sawUEIPAddress = true
+		}
+	}
+	if !sawApplicationID {
+		t.Errorf("BuildPDI for protocol %q: no Application ID IE, want one", r.Protocol)
+	}
+	if !sawUEIPAddress {
+		t.Errorf("BuildPDI with an \"assigned\" endpoint: no UE IP Address IE, want one")
+	}
+}
+
+func TestBuildPDIOmitsApplicationIDForIPProtocol(t *testing.T) {
+	r, err := ParseRule("permit out ip from 10.0.0.0/8 to any")
+	if err != nil {
+		t.Fatalf("ParseRule: %v", err)
+	}
+
+	pdi := r.BuildPDI(0, "10.0.0.1")
+
+	for _, child := range pdi.ChildIEs {
+		if child.Type == ie.ApplicationID {
+			t.Fatalf("BuildPDI for protocol %q: got an Application ID IE, want none", r.Protocol)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package pfcptx adds retransmission and duplicate detection on top of a
+// pfcpchan.Channel, per 3GPP TS 29.244 §7.3: a request sent with
+// SendRequest is retransmitted with exponential backoff until a response
+// with a matching sequence number arrives or N1 retransmissions have gone
+// unanswered, and an inbound request that
+// duplicates one already answered gets the cached response resent
+// instead of being handled twice.
+//
+// Transactor's Serve/RegisterHandler replace pfcpchan.Serve/Handler for
+// programs that need this, rather than layering on top of them: session-
+// server and session-client register a pfcptx.HandlerFunc per message
+// type and never implement pfcpchan.Handler. Programs with nothing to
+// retransmit or correlate, like simple-server, can skip this package and
+// use pfcpchan.Serve directly.
+package pfcptx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/wmnsk/go-pfcp/message"
+
+	"github.com/xandlom/rs-pfcp/go-interop/pfcpchan"
+)
+
+// Default timers, matching the values 3GPP TS 29.244 §7.3 recommends for
+// PFCP over an unreliable transport like UDP.
+const (
+	DefaultN1 = 2                // retransmissions after the first attempt
+	DefaultT1 = 3 * time.Second  // initial retransmission timeout, doubled on each retry
+	DefaultT2 = 10 * time.Second // how long a response is cached for duplicate requests
+)
+
+// HandlerFunc handles an inbound request and returns the response to
+// send back. A nil response with a nil error means the message was
+// handled without a reply.
+type HandlerFunc func(ctx context.Context, from net.Addr, msg message.Message) (message.Message, error)
+
+// Option configures a Transactor.
+type Option func(*Transactor)
+
+// WithN1 overrides the default number of retransmissions SendRequest
+// attempts after its first send.
+func WithN1(n int) Option { return func(t *Transactor) { t.n1 = n } }
+
+// WithT1 overrides the default initial retransmission timeout.
+func WithT1(d time.Duration) Option { return func(t *Transactor) { t.t1 = d } }
+
+// WithT2 overrides how long a sent response is cached for dedup.
+func WithT2(d time.Duration) Option { return func(t *Transactor) { t.t2 = d } }
+
+// WithOnParseError registers fn to be called whenever Serve fails to
+// parse an inbound packet.
+func WithOnParseError(fn func(error)) Option {
+	return func(t *Transactor) { t.onParseError = fn }
+}
+
+// WithOnResponseSent registers fn to be called after a handler's
+// response has been written successfully, e.g. so a caller can record
+// metrics without Transactor knowing about them.
+func WithOnResponseSent(fn func(message.Message)) Option {
+	return func(t *Transactor) { t.onResponseSent = fn }
+}
+
+type txKey struct {
+	peer string
+	seq  uint32
+}
+
+// pendingRequest is what SendRequest registers while it waits: resp is
+// handleInbound's handoff channel, and wantType is the response message
+// type (a request's type + 1, per 3GPP TS 29.244 §7.2.1's pairing)
+// that an inbound message must have to be considered a match. Without
+// it, an inbound request from the peer that happens to reuse the same
+// sequence number as this side's outstanding request - the two
+// directions keep independent counters, so collisions are possible -
+// would be misrouted here instead of dispatched to a handler.
+type pendingRequest struct {
+	resp     chan message.Message
+	wantType uint8
+}
+
+type cachedResponse struct {
+	msg     message.Message
+	expires time.Time
+}
+
+// Transactor is the single point both ends of a PFCP exchange use to
+// talk to a peer: SendRequest for messages this side originates,
+// RegisterHandler plus Serve for messages the peer originates.
+type Transactor struct {
+	ch pfcpchan.Channel
+
+	n1             int
+	t1             time.Duration
+	t2             time.Duration
+	onParseError   func(error)
+	onResponseSent func(message.Message)
+
+	mu       sync.Mutex
+	pending  map[txKey]pendingRequest
+	cache    map[txKey]*cachedResponse
+	handlers map[uint8]HandlerFunc
+}
+
+// New returns a Transactor that sends and receives over ch.
+func New(ch pfcpchan.Channel, opts ...Option) *Transactor {
+	t := &Transactor{
+		ch:       ch,
+		n1:       DefaultN1,
+		t1:       DefaultT1,
+		t2:       DefaultT2,
+		pending:  make(map[txKey]pendingRequest),
+		cache:    make(map[txKey]*cachedResponse),
+		handlers: make(map[uint8]HandlerFunc),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RegisterHandler registers fn to handle inbound messages of msgType,
+// e.g. message.MsgTypeSessionReportRequest.
+func (t *Transactor) RegisterHandler(msgType uint8, fn HandlerFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[msgType] = fn
+}
+
+// SendRequest sends req to peer and waits for the response matching
+// req's sequence number, retransmitting up to N1 times if none arrives:
+// the first wait is T1 and each later wait doubles the one before. It
+// returns ctx.Err() if ctx is done before a response does.
+func (t *Transactor) SendRequest(ctx context.Context, peer net.Addr, req message.Message) (message.Message, error) {
+	key := txKey{peer.String(), req.Sequence()}
+	resp := make(chan message.Message, 1)
+
+	t.mu.Lock()
+	t.pending[key] = pendingRequest{resp: resp, wantType: req.MessageType() + 1}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	timeout := t.t1
+	for attempt := 0; ; attempt++ {
+		if err := t.ch.WriteMessage(ctx, peer, req); err != nil {
+			return nil, fmt.Errorf("pfcptx: write %s: %w", req.MessageTypeName(), err)
+		}
+
+		timer := time.NewTimer(timeout)
+		select {
+		case m := <-resp:
+			timer.Stop()
+			return m, nil
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+			if attempt >= t.n1 {
+				return nil, fmt.Errorf("pfcptx: no response to %s seq %d after %d attempts", req.MessageTypeName(), req.Sequence(), attempt+1)
+			}
+			timeout *= 2
+		}
+	}
+}
+
+// Serve reads messages from the Channel passed to New until ctx is done
+// or the transport itself fails. Every message is either delivered to
+// the SendRequest call awaiting it, answered from the duplicate-response
+// cache, or dispatched to the handler registered for its type.
+func (t *Transactor) Serve(ctx context.Context) error {
+	var msg message.Message
+	for {
+		from, err := t.ch.ReadMessage(ctx, &msg)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if from != nil {
+				log.Printf("pfcptx: %v", err)
+				if t.onParseError != nil {
+					t.onParseError(err)
+				}
+				continue
+			}
+			return err
+		}
+
+		if err := t.handleInbound(ctx, from, msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *Transactor) handleInbound(ctx context.Context, from net.Addr, msg message.Message) error {
+	key := txKey{from.String(), msg.Sequence()}
+
+	t.mu.Lock()
+	if pr, ok := t.pending[key]; ok && msg.MessageType() == pr.wantType {
+		delete(t.pending, key)
+		t.mu.Unlock()
+		pr.resp <- msg
+		return nil
+	}
+
+	if cached, ok := t.cache[key]; ok && time.Now().Before(cached.expires) {
+		t.mu.Unlock()
+		if err := t.ch.WriteMessage(ctx, from, cached.msg); err != nil {
+			log.Printf("pfcptx: resend cached %s: %v", cached.msg.MessageTypeName(), err)
+		}
+		return nil
+	}
+
+	fn, ok := t.handlers[msg.MessageType()]
+	t.mu.Unlock()
+
+	if !ok {
+		log.Printf("pfcptx: no handler registered for %s", msg.MessageTypeName())
+		return nil
+	}
+
+	resp, err := fn(ctx, from, msg)
+	if err != nil {
+		return fmt.Errorf("pfcptx: handling %s: %w", msg.MessageTypeName(), err)
+	}
+	if resp == nil {
+		return nil
+	}
+
+	if err := t.ch.WriteMessage(ctx, from, resp); err != nil {
+		log.Printf("pfcptx: write %s: %v", resp.MessageTypeName(), err)
+		return nil
+	}
+	if t.onResponseSent != nil {
+		t.onResponseSent(resp)
+	}
+
+	t.mu.Lock()
+	t.cache[key] = &cachedResponse{msg: resp, expires: time.Now().Add(t.t2)}
+	t.mu.Unlock()
+	return nil
+}
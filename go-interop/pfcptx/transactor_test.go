@@ -0,0 +1,227 @@
+package pfcptx
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wmnsk/go-pfcp/ie"
+	"github.com/wmnsk/go-pfcp/message"
+)
+
+// fakeAddr is a minimal net.Addr for tests that never open a real socket.
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "fake" }
+func (a fakeAddr) String() string  { return string(a) }
+
+// fakeChannel is an in-memory pfcpchan.Channel: ReadMessage delivers
+// whatever's pushed via deliver, and WriteMessage records its argument
+// instead of putting anything on a wire.
+type fakeChannel struct {
+	inbox chan inboundMsg
+
+	mu   sync.Mutex
+	sent []message.Message
+}
+
+type inboundMsg struct {
+	from net.Addr
+	msg  message.Message
+}
+
+func newFakeChannel() *fakeChannel {
+	return &fakeChannel{inbox: make(chan inboundMsg, 16)}
+}
+
+func (f *fakeChannel) deliver(from net.Addr, msg message.Message) {
+	f.inbox <- inboundMsg{from, msg}
+}
+
+func (f *fakeChannel) ReadMessage(ctx context.Context, out *message.Message) (net.Addr, error) {
+	select {
+	case m := <-f.inbox:
+		*out = m.msg
+		return m.from, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeChannel) WriteMessage(ctx context.Context, addr net.Addr, m message.Message) error {
+	f.mu.Lock()
+	f.sent = append(f.sent, m)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeChannel) SetMaxSize(int) {}
+
+func (f *fakeChannel) writes() []message.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]message.Message, len(f.sent))
+	copy(out, f.sent)
+	return out
+}
+
+func TestTransactorSendRequestRetransmitsUntilResponse(t *testing.T) {
+	ch := newFakeChannel()
+	tx := New(ch, WithT1(5*time.Millisecond), WithN1(5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tx.Serve(ctx)
+
+	req := message.NewHeartbeatRequest(1, ie.NewRecoveryTimeStamp(time.Now()), nil)
+	peer := fakeAddr("peer:8805")
+
+	// Let SendRequest retransmit a couple of times before answering, so
+	// the test exercises the backoff loop rather than the first attempt.
+	go func() {
+		time.Sleep(25 * time.Millisecond)
+		resp := message.NewHeartbeatResponse(1, ie.NewRecoveryTimeStamp(time.Now()))
+		ch.deliver(peer, resp)
+	}()
+
+	got, err := tx.SendRequest(ctx, peer, req)
+	if err != nil {
+		t.Fatalf("SendRequest: %v", err)
+	}
+	if got.MessageTypeName() != "Heartbeat Response" {
+		t.Fatalf("SendRequest returned %s, want Heartbeat Response", got.MessageTypeName())
+	}
+
+	if n := len(ch.writes()); n < 2 {
+		t.Fatalf("WriteMessage called %d time(s), want at least 2 (request retransmitted before the response arrived)", n)
+	}
+}
+
+func TestTransactorSendRequestGivesUpAfterN1Retries(t *testing.T) {
+	ch := newFakeChannel()
+	tx := New(ch, WithT1(2*time.Millisecond), WithN1(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tx.Serve(ctx)
+
+	req := message.NewHeartbeatRequest(2, ie.NewRecoveryTimeStamp(time.Now()), nil)
+	peer := fakeAddr("peer:8805")
+
+	_, err := tx.SendRequest(ctx, peer, req)
+	if err == nil {
+		t.Fatal("SendRequest: got nil error, want one (no response ever arrives)")
+	}
+
+	if want := 3; len(ch.writes()) != want { // first attempt + N1 retries
+		t.Fatalf("WriteMessage called %d time(s), want %d", len(ch.writes()), want)
+	}
+}
+
+func TestTransactorServeDedupsRetransmittedRequest(t *testing.T) {
+	ch := newFakeChannel()
+	tx := New(ch, WithT2(time.Minute))
+
+	var handled int
+	tx.RegisterHandler(message.MsgTypeHeartbeatRequest, func(ctx context.Context, from net.Addr, msg message.Message) (message.Message, error) {
+		handled++
+		return message.NewHeartbeatResponse(msg.Sequence(), ie.NewRecoveryTimeStamp(time.Now())), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tx.Serve(ctx)
+
+	peer := fakeAddr("peer:8805")
+	req := message.NewHeartbeatRequest(7, ie.NewRecoveryTimeStamp(time.Now()), nil)
+
+	ch.deliver(peer, req)
+	waitForWrites(t, ch, 1)
+
+	// Same peer, same sequence number: a retransmit of the request
+	// already answered, so the handler must not run a second time.
+	ch.deliver(peer, req)
+	waitForWrites(t, ch, 2)
+
+	if handled != 1 {
+		t.Fatalf("handler ran %d time(s), want 1 (second request was a duplicate)", handled)
+	}
+}
+
+func TestTransactorDoesNotMisrouteInboundRequestReusingPendingSequence(t *testing.T) {
+	ch := newFakeChannel()
+	tx := New(ch, WithT1(time.Hour), WithN1(0))
+
+	reportHandled := make(chan message.Message, 1)
+	tx.RegisterHandler(message.MsgTypeSessionReportRequest, func(ctx context.Context, from net.Addr, msg message.Message) (message.Message, error) {
+		reportHandled <- msg
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tx.Serve(ctx)
+
+	peer := fakeAddr("peer:8805")
+	req := message.NewHeartbeatRequest(9, ie.NewRecoveryTimeStamp(time.Now()), nil)
+
+	sendDone := make(chan struct {
+		resp message.Message
+		err  error
+	}, 1)
+	go func() {
+		resp, err := tx.SendRequest(ctx, peer, req)
+		sendDone <- struct {
+			resp message.Message
+			err  error
+		}{resp, err}
+	}()
+
+	// The peer's own sequence counter is independent of ours, so it can
+	// legitimately send us a request reusing sequence 9 while our
+	// HeartbeatRequest with that same sequence is still outstanding.
+	waitForWrites(t, ch, 1)
+	collidingReq := message.NewSessionReportRequest(0, 0, 0, 9, 0)
+	ch.deliver(peer, collidingReq)
+
+	select {
+	case got := <-reportHandled:
+		if got.Sequence() != 9 {
+			t.Fatalf("handler got sequence %d, want 9", got.Sequence())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Session Report Request handler never ran: it was misrouted into the pending HeartbeatRequest's response channel")
+	}
+
+	resp := message.NewHeartbeatResponse(9, ie.NewRecoveryTimeStamp(time.Now()))
+	ch.deliver(peer, resp)
+
+	select {
+	case got := <-sendDone:
+		if got.err != nil {
+			t.Fatalf("SendRequest: %v", got.err)
+		}
+		if got.resp.MessageTypeName() != "Heartbeat Response" {
+			t.Fatalf("SendRequest returned %s, want Heartbeat Response", got.resp.MessageTypeName())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SendRequest never returned: the real response was never delivered")
+	}
+}
+
+// waitForWrites polls until ch has recorded at least n writes or fails
+// the test after a short timeout; Serve runs in its own goroutine so the
+// test can't just assert immediately after deliver.
+func waitForWrites(t *testing.T, ch *fakeChannel, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(ch.writes()) >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("writes() = %d after 1s, want at least %d", len(ch.writes()), n)
+}
@@ -4,30 +4,35 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/wmnsk/go-pfcp/ie"
 	"github.com/wmnsk/go-pfcp/message"
-)
 
-func handleSessionReportRequest(conn *net.UDPConn, data []byte) error {
-	msg, err := message.Parse(data)
-	if err != nil {
-		return fmt.Errorf("failed to parse Session Report Request: %v", err)
-	}
+	"github.com/xandlom/rs-pfcp/go-interop/pfcpchan"
+	"github.com/xandlom/rs-pfcp/go-interop/pfcpsdf"
+	"github.com/xandlom/rs-pfcp/go-interop/pfcptx"
+)
 
+// handleSessionReportRequest answers a Session Report Request (the
+// server's quota-exhaustion notification) with RequestAccepted. It is
+// registered with the Transactor instead of being polled for.
+func handleSessionReportRequest(ctx context.Context, from net.Addr, msg message.Message) (message.Message, error) {
 	reportReq, ok := msg.(*message.SessionReportRequest)
 	if !ok {
-		return fmt.Errorf("not a Session Report Request")
+		return nil, fmt.Errorf("unexpected message type %T for Session Report Request", msg)
 	}
 
 	fmt.Printf("  Received Session Report Request for session 0x%016x\n", reportReq.SEID())
-	
-	// Check for usage reports in IEs
+
 	for _, reqIE := range reportReq.IEs {
 		if reqIE.Type == ie.UsageReportWithinSessionReportRequest {
 			fmt.Println("    Contains Usage Report - quota exhausted!")
@@ -40,38 +45,127 @@ func handleSessionReportRequest(conn *net.UDPConn, data []byte) error {
 		}
 	}
 
-	// Send Session Report Response with RequestAccepted
 	response := message.NewSessionReportResponse(
-		0, 0,                             // mp, fo flags
-		reportReq.SEID(),                 // SEID
-		reportReq.SequenceNumber,         // Sequence
-		0,                                // Priority
+		0, 0, // mp, fo flags
+		reportReq.SEID(),                     // SEID
+		reportReq.SequenceNumber,             // Sequence
+		0,                                    // Priority
 		ie.NewCause(ie.CauseRequestAccepted), // Cause
 	)
+	fmt.Println("  Sent Session Report Response (RequestAccepted)")
+	return response, nil
+}
 
-	respData, err := response.Marshal()
+// loadRules reads one SDF flow-description rule per line from path,
+// skipping blank lines and "#"-prefixed comments.
+func loadRules(path string) ([]*pfcpsdf.Rule, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to marshal Session Report Response: %v", err)
+		return nil, fmt.Errorf("open rules file: %w", err)
 	}
+	defer f.Close()
 
-	_, err = conn.Write(respData)
-	if err != nil {
-		return fmt.Errorf("failed to send Session Report Response: %v", err)
+	var rules []*pfcpsdf.Rule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := pfcpsdf.ParseRule(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
 	}
 
-	fmt.Println("  Sent Session Report Response (RequestAccepted)")
-	return nil
+	return rules, nil
+}
+
+// pdrsAndFARsFromRules turns each parsed rule into one Create PDR, routed
+// to the core for "out" (uplink) rules and to access for "in" (downlink)
+// rules, and returns the Create FAR IEs those PDRs reference.
+func pdrsAndFARsFromRules(rules []*pfcpsdf.Rule, ueIP string) (pdrs, fars []*ie.IE) {
+	const (
+		uplinkFARID   = 1
+		downlinkFARID = 2
+	)
+
+	var needUplinkFAR, needDownlinkFAR bool
+	pdrID := uint16(1)
+	precedence := uint32(100)
+
+	for _, rule := range rules {
+		var srcInterface uint8
+		var farID uint32
+		switch rule.Direction {
+		case pfcpsdf.DirectionOut:
+			srcInterface = ie.SrcInterfaceAccess
+			farID = uplinkFARID
+			needUplinkFAR = true
+		case pfcpsdf.DirectionIn:
+			srcInterface = ie.SrcInterfaceCore
+			farID = downlinkFARID
+			needDownlinkFAR = true
+		}
+
+		pdrs = append(pdrs, ie.NewCreatePDR(
+			ie.NewPDRID(pdrID),
+			ie.NewPrecedence(precedence),
+			rule.BuildPDI(srcInterface, ueIP),
+			ie.NewFARID(farID),
+		))
+
+		pdrID++
+		precedence += 100
+	}
+
+	if needUplinkFAR {
+		fars = append(fars, ie.NewCreateFAR(
+			ie.NewFARID(uplinkFARID),
+			ie.NewApplyAction(0, 0, 0, 0, 1), // FORW flag
+			ie.NewForwardingParameters(
+				ie.NewDestinationInterface(ie.DstInterfaceCore),
+			),
+		))
+	}
+	if needDownlinkFAR {
+		fars = append(fars, ie.NewCreateFAR(
+			ie.NewFARID(downlinkFARID),
+			ie.NewApplyAction(0, 0, 0, 0, 1), // FORW flag
+			ie.NewForwardingParameters(
+				ie.NewDestinationInterface(ie.DstInterfaceAccess),
+			),
+		))
+	}
+
+	return pdrs, fars
 }
 
 func main() {
 	var (
 		serverAddr = flag.String("address", "127.0.0.1", "Server address to connect to")
-		port       = flag.Int("port", 8805, "Server port to connect to") 
+		port       = flag.Int("port", 8805, "Server port to connect to")
 		sessions   = flag.Int("sessions", 1, "Number of sessions to create")
 		iface      = flag.String("interface", "lo", "Network interface to use (for compatibility)")
+		rulesFile  = flag.String("rules-file", "", "Path to a file of SDF flow-description rules, one per line (each becomes an uplink or downlink PDR)")
 	)
 	flag.Parse()
 
+	var rules []*pfcpsdf.Rule
+	if *rulesFile != "" {
+		var err error
+		rules, err = loadRules(*rulesFile)
+		if err != nil {
+			log.Fatalf("Failed to load rules file: %v", err)
+		}
+		fmt.Printf("Loaded %d SDF rule(s) from %s\n", len(rules), *rulesFile)
+	}
+
 	// Resolve server address
 	serverUDPAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", *serverAddr, *port))
 	if err != nil {
@@ -95,29 +189,29 @@ func main() {
 		log.Fatal("Failed to get IPv4 local address")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := pfcpchan.NewUDPChannel(conn)
+	tx := pfcptx.New(ch)
+	tx.RegisterHandler(message.MsgTypeSessionReportRequest, handleSessionReportRequest)
+
+	go func() {
+		if err := tx.Serve(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Transactor loop exited: %v", err)
+		}
+	}()
+
 	// 1. Association Setup
 	fmt.Println("Sending Association Setup Request...")
-	nodeID := ie.NewNodeID("", "", nodeIP.String())  // Use string instead of IP
+	nodeID := ie.NewNodeID("", "", nodeIP.String()) // Use string instead of IP
 	recoveryTS := ie.NewRecoveryTimeStamp(time.Now())
-	
+
 	assocReq := message.NewAssociationSetupRequest(1, nodeID, recoveryTS)
-	
-	reqData, err := assocReq.Marshal()
-	if err != nil {
-		log.Fatalf("Failed to marshal Association Setup Request: %v", err)
-	}
 
-	_, err = conn.Write(reqData)
-	if err != nil {
+	if _, err := tx.SendRequest(ctx, serverUDPAddr, assocReq); err != nil {
 		log.Fatalf("Failed to send Association Setup Request: %v", err)
 	}
-
-	// Read response
-	buf := make([]byte, 1500)
-	_, err = conn.Read(buf)
-	if err != nil {
-		log.Fatalf("Failed to read Association Setup Response: %v", err)
-	}
 	fmt.Println("Received Association Setup Response.")
 
 	// Process sessions
@@ -127,112 +221,73 @@ func main() {
 
 		// 2. Session Establishment
 		fmt.Printf("[%d] Sending Session Establishment Request...\n", seid)
-		
+
 		// Create F-SEID
 		fseid := ie.NewFSEID(seid+0x0102030405060708, nodeIP, nil)
-		
-		// Create uplink PDR (PDR ID 1, precedence 100)
-		uplinkPDR := ie.NewCreatePDR(
-			ie.NewPDRID(1),
-			ie.NewPrecedence(100),
-			ie.NewPDI(
-				ie.NewSourceInterface(ie.SrcInterfaceAccess),
-			),
-			ie.NewFARID(1),
-		)
 
-		// Create downlink PDR (PDR ID 2, precedence 200) 
-		downlinkPDR := ie.NewCreatePDR(
-			ie.NewPDRID(2),
-			ie.NewPrecedence(200),
-			ie.NewPDI(
-				ie.NewSourceInterface(ie.SrcInterfaceCore),
-			),
-			ie.NewFARID(1),
-		)
+		var pdrs, fars []*ie.IE
+		if len(rules) > 0 {
+			pdrs, fars = pdrsAndFARsFromRules(rules, nodeIP.String())
+		} else {
+			// No -rules-file given: fall back to the trivial uplink/downlink
+			// demo PDRs this client has always sent.
+			pdrs = []*ie.IE{
+				ie.NewCreatePDR(
+					ie.NewPDRID(1),
+					ie.NewPrecedence(100),
+					ie.NewPDI(
+						ie.NewSourceInterface(ie.SrcInterfaceAccess),
+					),
+					ie.NewFARID(1),
+				),
+				ie.NewCreatePDR(
+					ie.NewPDRID(2),
+					ie.NewPrecedence(200),
+					ie.NewPDI(
+						ie.NewSourceInterface(ie.SrcInterfaceCore),
+					),
+					ie.NewFARID(1),
+				),
+			}
+			fars = []*ie.IE{
+				ie.NewCreateFAR(
+					ie.NewFARID(1),
+					ie.NewApplyAction(0, 0, 0, 0, 1), // FORW flag
+					ie.NewForwardingParameters(
+						ie.NewDestinationInterface(ie.DstInterfaceCore),
+					),
+				),
+			}
+		}
 
-		// Create uplink FAR (forward to core)
-		uplinkFAR := ie.NewCreateFAR(
-			ie.NewFARID(1),
-			ie.NewApplyAction(0, 0, 0, 0, 1), // FORW flag
-			ie.NewForwardingParameters(
-				ie.NewDestinationInterface(ie.DstInterfaceCore),
-			),
-		)
+		sessionIEs := []*ie.IE{nodeID, fseid}
+		sessionIEs = append(sessionIEs, pdrs...)
+		sessionIEs = append(sessionIEs, fars...)
 
 		sessionReq := message.NewSessionEstablishmentRequest(
-			0, 0,           // mp, fo flags
-			seid,           // SEID
-			2,              // sequence number
-			0,              // priority
-			nodeID,         // Node ID
-			fseid,          // F-SEID
-			uplinkPDR,      // Create PDR
-			downlinkPDR,    // Create PDR
-			uplinkFAR,      // Create FAR
+			0, 0, // mp, fo flags
+			seid,          // SEID
+			2,             // sequence number
+			0,             // priority
+			sessionIEs..., // Node ID, F-SEID, Create PDR(s), Create FAR(s)
 		)
 
-		reqData, err = sessionReq.Marshal()
-		if err != nil {
-			log.Printf("Failed to marshal Session Establishment Request: %v", err)
-			continue
-		}
-
-		_, err = conn.Write(reqData)
-		if err != nil {
+		if _, err := tx.SendRequest(ctx, serverUDPAddr, sessionReq); err != nil {
 			log.Printf("Failed to send Session Establishment Request: %v", err)
 			continue
 		}
-
-		_, err = conn.Read(buf)
-		if err != nil {
-			log.Printf("Failed to read Session Establishment Response: %v", err)
-			continue
-		}
 		fmt.Printf("[%d] Received Session Establishment Response.\n", seid)
 
-		// Listen for Session Report Requests (quota exhaustion notifications)
-		fmt.Printf("[%d] Listening for Session Report Requests...\n", seid)
-		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
-
-		for {
-			n, err := conn.Read(buf)
-			if err != nil {
-				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-					fmt.Printf("[%d] No Session Report Request received within timeout\n", seid)
-					break
-				}
-				fmt.Printf("[%d] Error receiving: %v\n", seid, err)
-				break
-			}
-
-			data := buf[:n]
-			msg, err := message.Parse(data)
-			if err != nil {
-				fmt.Printf("[%d] Failed to parse message: %v\n", seid, err)
-				continue
-			}
-
-			switch msg.(type) {
-			case *message.SessionReportRequest:
-				err = handleSessionReportRequest(conn, data)
-				if err != nil {
-					fmt.Printf("[%d] Error handling Session Report Request: %v\n", seid, err)
-				}
-				goto nextPhase // Exit listening loop after handling report
-
-			default:
-				fmt.Printf("[%d] Received unexpected message: %s\n", seid, msg.MessageTypeName())
-			}
-		}
-
-	nextPhase:
-		// Reset read deadline
-		conn.SetReadDeadline(time.Time{})
+		// Any Session Report Request the server sends for this session
+		// (quota exhaustion) is handled as soon as it arrives by the
+		// handler registered with tx, not polled for here. Give it a
+		// moment to show up before moving on.
+		fmt.Printf("[%d] Waiting for Session Report Requests...\n", seid)
+		time.Sleep(3 * time.Second)
 
 		// 3. Session Modification
 		fmt.Printf("[%d] Sending Session Modification Request...\n", seid)
-		
+
 		// Create modified PDR with higher precedence
 		modifiedPDR := ie.NewUpdatePDR(
 			ie.NewPDRID(1),
@@ -240,63 +295,37 @@ func main() {
 		)
 
 		sessionModReq := message.NewSessionModificationRequest(
-			0, 0,           // mp, fo flags
-			seid,           // SEID
-			3,              // sequence number
-			0,              // priority
-			fseid,          // F-SEID
-			modifiedPDR,    // Update PDR
+			0, 0, // mp, fo flags
+			seid,        // SEID
+			3,           // sequence number
+			0,           // priority
+			fseid,       // F-SEID
+			modifiedPDR, // Update PDR
 		)
 
-		reqData, err = sessionModReq.Marshal()
-		if err != nil {
-			log.Printf("Failed to marshal Session Modification Request: %v", err)
-			continue
-		}
-
-		_, err = conn.Write(reqData)
-		if err != nil {
+		if _, err := tx.SendRequest(ctx, serverUDPAddr, sessionModReq); err != nil {
 			log.Printf("Failed to send Session Modification Request: %v", err)
 			continue
 		}
-
-		_, err = conn.Read(buf)
-		if err != nil {
-			log.Printf("Failed to read Session Modification Response: %v", err)
-			continue
-		}
 		fmt.Printf("[%d] Received Session Modification Response.\n", seid)
 
 		// 4. Session Deletion
 		fmt.Printf("[%d] Sending Session Deletion Request...\n", seid)
-		
+
 		sessionDelReq := message.NewSessionDeletionRequest(
-			0, 0,           // mp, fo flags
-			seid,           // SEID
-			4,              // sequence number
-			0,              // priority
-			fseid,          // F-SEID
+			0, 0, // mp, fo flags
+			seid,  // SEID
+			4,     // sequence number
+			0,     // priority
+			fseid, // F-SEID
 		)
 
-		reqData, err = sessionDelReq.Marshal()
-		if err != nil {
-			log.Printf("Failed to marshal Session Deletion Request: %v", err)
-			continue
-		}
-
-		_, err = conn.Write(reqData)
-		if err != nil {
+		if _, err := tx.SendRequest(ctx, serverUDPAddr, sessionDelReq); err != nil {
 			log.Printf("Failed to send Session Deletion Request: %v", err)
 			continue
 		}
-
-		_, err = conn.Read(buf)
-		if err != nil {
-			log.Printf("Failed to read Session Deletion Response: %v", err)
-			continue
-		}
 		fmt.Printf("[%d] Received Session Deletion Response.\n", seid)
 	}
 
 	fmt.Println("\nAll sessions completed successfully!")
-}
\ No newline at end of file
+}
@@ -1,17 +1,32 @@
 // Fixed Go PFCP Session Server using correct go-pfcp v0.0.24 API
 // This server implements proper PFCP message handling for Rust interoperability
-
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/wmnsk/go-pfcp/ie"
 	"github.com/wmnsk/go-pfcp/message"
+
+	"github.com/xandlom/rs-pfcp/go-interop/datapath"
+	"github.com/xandlom/rs-pfcp/go-interop/pfcpchan"
+	"github.com/xandlom/rs-pfcp/go-interop/pfcptx"
+)
+
+// usagePollInterval is how often a session's URRs are polled for usage
+// reports once it has at least one installed; usageMaxEmptyPolls bounds
+// how long the poller goroutine survives after a session stops producing
+// reports, so a forgotten session doesn't leak a goroutine forever.
+const (
+	usagePollInterval  = 2 * time.Second
+	usageMaxEmptyPolls = 3
 )
 
 type SessionInfo struct {
@@ -20,254 +35,380 @@ type SessionInfo struct {
 	Sequence   uint32
 }
 
-func main() {
-	var (
-		addr = flag.String("addr", "127.0.0.1:8805", "Local address to listen on")
-	)
-	flag.Parse()
+// sessionHandler registers its Handle* methods with a pfcptx.Transactor.
+// It keeps the per-SEID bookkeeping the original switch statement used to
+// close over directly, and drives PDR/FAR/QER/URR state through a
+// datapath.Datapath instead of dropping it on the floor.
+type sessionHandler struct {
+	dp       datapath.Datapath
+	tx       *pfcptx.Transactor
+	sessions map[uint64]*SessionInfo
+
+	// nextSequence is also bumped from the per-session usage-polling
+	// goroutine, so it's an atomic rather than a plain uint32.
+	nextSequence atomic.Uint32
+}
 
-	// Parse the address
-	udpAddr, err := net.ResolveUDPAddr("udp", *addr)
-	if err != nil {
-		log.Fatalf("Failed to resolve address: %v", err)
+func newSessionHandler(dp datapath.Datapath, tx *pfcptx.Transactor) *sessionHandler {
+	h := &sessionHandler{
+		dp:       dp,
+		tx:       tx,
+		sessions: make(map[uint64]*SessionInfo),
 	}
+	h.nextSequence.Store(1000)
+	return h
+}
 
-	// Create UDP listener
-	conn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		log.Fatalf("Failed to listen on UDP: %v", err)
+// applyCreateRules installs every Create PDR/FAR/QER/URR IE found in ies
+// into h.dp for seid.
+func (h *sessionHandler) applyCreateRules(seid uint64, ies []*ie.IE) error {
+	for _, reqIE := range ies {
+		var err error
+		switch reqIE.Type {
+		case ie.CreatePDR:
+			err = h.dp.AddPDR(seid, reqIE)
+		case ie.CreateFAR:
+			err = h.dp.AddFAR(seid, reqIE)
+		case ie.CreateQER:
+			err = h.dp.AddQER(seid, reqIE)
+		case ie.CreateURR:
+			err = h.dp.AddURR(seid, reqIE)
+		}
+		if err != nil {
+			return err
+		}
 	}
-	defer conn.Close()
-
-	fmt.Printf("Fixed Go PFCP Server listening on %s\n", *addr)
-	fmt.Printf("Socket bound successfully to %s\n", conn.LocalAddr())
+	return nil
+}
 
-	sessions := make(map[uint64]*SessionInfo)
-	nextSequence := uint32(1000)
+// applyModifyRules applies every Create/Update/Remove PDR/FAR/QER/URR IE
+// found in ies to h.dp for seid.
+func (h *sessionHandler) applyModifyRules(seid uint64, ies []*ie.IE) error {
+	for _, reqIE := range ies {
+		var err error
+		switch reqIE.Type {
+		case ie.CreatePDR:
+			err = h.dp.AddPDR(seid, reqIE)
+		case ie.CreateFAR:
+			err = h.dp.AddFAR(seid, reqIE)
+		case ie.CreateQER:
+			err = h.dp.AddQER(seid, reqIE)
+		case ie.CreateURR:
+			err = h.dp.AddURR(seid, reqIE)
+		case ie.UpdatePDR:
+			err = h.dp.UpdatePDR(seid, reqIE)
+		case ie.UpdateFAR:
+			err = h.dp.UpdateFAR(seid, reqIE)
+		case ie.UpdateQER:
+			err = h.dp.UpdateQER(seid, reqIE)
+		case ie.UpdateURR:
+			err = h.dp.UpdateURR(seid, reqIE)
+		case ie.RemovePDR:
+			var id uint16
+			if id, err = reqIE.PDRID(); err == nil {
+				err = h.dp.DeletePDR(seid, id)
+			}
+		case ie.RemoveFAR:
+			var id uint32
+			if id, err = reqIE.FARID(); err == nil {
+				err = h.dp.DeleteFAR(seid, id)
+			}
+		case ie.RemoveQER:
+			var id uint32
+			if id, err = reqIE.QERID(); err == nil {
+				err = h.dp.DeleteQER(seid, id)
+			}
+		case ie.RemoveURR:
+			var id uint32
+			if id, err = reqIE.URRID(); err == nil {
+				err = h.dp.DeleteURR(seid, id)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	buf := make([]byte, 1500)
+// pollSessionUsage polls h.dp for seid's usage reports every
+// usagePollInterval and forwards each as a Session Report Request, until
+// ctx is done or usageMaxEmptyPolls consecutive polls come back empty.
+func (h *sessionHandler) pollSessionUsage(ctx context.Context, clientAddr *net.UDPAddr, seid uint64) {
+	ticker := time.NewTicker(usagePollInterval)
+	defer ticker.Stop()
 
+	emptyPolls := 0
 	for {
-		n, clientAddr, err := conn.ReadFromUDP(buf)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reports, err := h.dp.PollUsage(seid)
 		if err != nil {
-			log.Printf("Failed to read UDP packet: %v", err)
+			log.Printf("PollUsage(0x%016x): %v", seid, err)
+			return
+		}
+		if len(reports) == 0 {
+			emptyPolls++
+			if emptyPolls >= usageMaxEmptyPolls {
+				return
+			}
 			continue
 		}
+		emptyPolls = 0
 
-		data := buf[:n]
-		fmt.Printf("Received %d bytes from %s\n", n, clientAddr)
+		for _, report := range reports {
+			h.sendSessionReportRequest(ctx, clientAddr, seid, report)
+		}
+	}
+}
 
-		// Parse PFCP message
-		msg, err := message.Parse(data)
-		if err != nil {
-			log.Printf("Failed to parse PFCP message: %v", err)
+// sendSessionReportRequest sends a Session Report Request through
+// h.tx.SendRequest, so a lost request or response is retransmitted
+// rather than leaving the quota-exhaustion notification unacknowledged.
+func (h *sessionHandler) sendSessionReportRequest(ctx context.Context, clientAddr *net.UDPAddr, seid uint64, report datapath.UsageReport) {
+	fmt.Printf("  [%s] Sending Session Report Request for session 0x%016x (URR %d)\n", report.Trigger, seid, report.URRID)
+
+	reportIEs := []*ie.IE{
+		ie.NewReportType(0, 1, 0, 0), // USAR flag set
+		ie.NewUsageReportWithinSessionReportRequest(
+			ie.NewURRID(report.URRID),
+			ie.NewURSEQN(1),
+			ie.NewUsageReportTrigger(0, 1, 0, 0, 0, 0, 0, 0), // Volume threshold trigger
+		),
+	}
+
+	reportRequest := message.NewSessionReportRequest(
+		0, 0, // mp, fo flags
+		seid,                  // SEID
+		h.nextSequence.Add(1), // Sequence
+		0,                     // Priority
+		reportIEs...,          // IEs
+	)
+	reportRequestsSent.WithLabelValues(report.Trigger).Inc()
+
+	resp, err := h.tx.SendRequest(ctx, clientAddr, reportRequest)
+	if err != nil {
+		log.Printf("Session Report Request for session 0x%016x: %v", seid, err)
+		return
+	}
+	reportResponsesReceived.Inc()
+	fmt.Printf("  Received Session Report Response for session 0x%016x - quota exhaustion acknowledged\n", seid)
+	if srr, ok := resp.(*message.SessionReportResponse); ok && srr.Cause != nil {
+		fmt.Printf("  Response cause: %d\n", srr.Cause.Payload[0])
+	}
+}
+
+func (h *sessionHandler) HandleAssociationSetupRequest(ctx context.Context, from net.Addr, rawMsg message.Message) (message.Message, error) {
+	msg, ok := rawMsg.(*message.AssociationSetupRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T for Association Setup Request", rawMsg)
+	}
+	start := time.Now()
+	defer observeRequest(msg.MessageTypeName(), start)
+	messagesTotal.WithLabelValues(msg.MessageTypeName(), directionReceived, "").Inc()
+
+	fmt.Println("Processing Association Setup Request")
+
+	response := message.NewAssociationSetupResponse(
+		msg.SequenceNumber,
+		ie.NewNodeID("", "", "127.0.0.1"),    // FQDN format Node ID
+		ie.NewCause(ie.CauseRequestAccepted), // Request accepted cause
+		ie.NewRecoveryTimeStamp(time.Now()),  // Current recovery timestamp
+	)
+	fmt.Println("Sent Association Setup Response")
+	return response, nil
+}
+
+func (h *sessionHandler) HandleSessionEstablishmentRequest(ctx context.Context, from net.Addr, rawMsg message.Message) (message.Message, error) {
+	msg, ok := rawMsg.(*message.SessionEstablishmentRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T for Session Establishment Request", rawMsg)
+	}
+	start := time.Now()
+	defer observeRequest(msg.MessageTypeName(), start)
+	messagesTotal.WithLabelValues(msg.MessageTypeName(), directionReceived, "").Inc()
+
+	seid := msg.SEID()
+	fmt.Printf("  Session ID: 0x%016x\n", seid)
+
+	if err := h.applyCreateRules(seid, msg.IEs); err != nil {
+		return nil, fmt.Errorf("applying create rules for session 0x%016x: %w", seid, err)
+	}
+
+	// Build a Created PDR response IE for every PDR in the request, each
+	// carrying a local F-TEID the client should tunnel uplink traffic to.
+	var createdPDRs []*ie.IE
+	for _, reqIE := range msg.IEs {
+		if reqIE.Type != ie.CreatePDR {
 			continue
 		}
+		pdrID, err := reqIE.PDRID()
+		if err != nil {
+			return nil, fmt.Errorf("session 0x%016x: %w", seid, err)
+		}
 
-		fmt.Printf("Received %s from %s\n", msg.MessageTypeName(), clientAddr)
+		teid := uint32(0x12345678) + uint32(pdrID)
+		localIP := net.IPv4(192, 168, 1, 100)
 
-		switch msg := msg.(type) {
-		case *message.AssociationSetupRequest:
-			fmt.Println("Processing Association Setup Request")
+		createdPDRs = append(createdPDRs, ie.NewCreatedPDR(
+			ie.NewPDRID(pdrID),
+			ie.NewFTEID(0x01, teid, localIP, nil, 0), // IPv4 flag, TEID, IPv4 addr
+		))
+		fmt.Printf("      → Created PDR: PDR ID %d, F-TEID: 0x%08x@192.168.1.100\n", pdrID, teid)
+	}
 
-			// Create Association Setup Response using correct API
-			response := message.NewAssociationSetupResponse(
-				msg.SequenceNumber,
-				ie.NewNodeID("", "", "127.0.0.1"),               // FQDN format Node ID
-				ie.NewCause(ie.CauseRequestAccepted),             // Request accepted cause
-				ie.NewRecoveryTimeStamp(time.Now()),             // Current recovery timestamp
-			)
+	clientAddr, ok := from.(*net.UDPAddr)
+	if !ok {
+		return nil, fmt.Errorf("unexpected peer address type %T", from)
+	}
 
-			respData, err := response.Marshal()
-			if err != nil {
-				log.Printf("Failed to marshal Association Setup Response: %v", err)
-				continue
-			}
+	h.sessions[seid] = &SessionInfo{
+		SEID:       seid,
+		ClientAddr: clientAddr,
+		Sequence:   h.nextSequence.Load(),
+	}
+	sessionsActive.Set(float64(len(h.sessions)))
 
-			_, err = conn.WriteToUDP(respData, clientAddr)
-			if err != nil {
-				log.Printf("Failed to send Association Setup Response: %v", err)
-			} else {
-				fmt.Println("Sent Association Setup Response")
-			}
+	responseIEs := []*ie.IE{
+		ie.NewNodeID("", "", "127.0.0.1"),
+		ie.NewCause(ie.CauseRequestAccepted),
+		msg.CPFSEID, // Echo back the F-SEID from request
+	}
+	responseIEs = append(responseIEs, createdPDRs...)
+
+	response := message.NewSessionEstablishmentResponse(
+		0, 0, // mp, fo flags
+		seid,               // SEID
+		msg.SequenceNumber, // Sequence number
+		0,                  // Priority
+		responseIEs...,
+	)
 
-		case *message.SessionEstablishmentRequest:
-			seid := msg.SEID()
-			fmt.Printf("  Session ID: 0x%016x\n", seid)
-
-			// Process Create PDR IEs from the request
-			var createdPDRs []*ie.IE
-			var pdrCount int
-
-			// Access IEs from the message
-			for _, reqIE := range msg.IEs {
-				if reqIE.Type == ie.CreatePDR {
-					pdrCount++
-					// Extract PDR ID from the CreatePDR IE (simplified)
-					// In real implementation, we would properly parse the CreatePDR IE
-					pdrID := uint16(pdrCount) // Use sequence as PDR ID for demo
-
-					fmt.Printf("    CreatePdr %d: PDR ID: %d\n", pdrCount, pdrID)
-
-					// Create a local F-TEID for this PDR
-					teid := uint32(0x12345678) + uint32(pdrID)
-					localIP := net.IPv4(192, 168, 1, 100)
-
-					// Create Created PDR IE with proper F-TEID
-					createdPDR := ie.NewCreatedPDR(
-						ie.NewPDRID(pdrID),
-						ie.NewFTEID(0x01, teid, localIP, nil, 0), // IPv4 flag, TEID, IPv4 addr
-					)
-					createdPDRs = append(createdPDRs, createdPDR)
-
-					fmt.Printf("      → Created PDR: PDR ID %d, F-TEID: 0x%08x@192.168.1.100\n", 
-						pdrID, teid)
-				}
-			}
+	fmt.Printf("Sent Session Establishment Response for session 0x%016x\n", seid)
 
-			// Store session information
-			sessions[seid] = &SessionInfo{
-				SEID:       seid,
-				ClientAddr: clientAddr,
-				Sequence:   nextSequence,
-			}
+	hasURR := false
+	for _, reqIE := range msg.IEs {
+		if reqIE.Type == ie.CreateURR {
+			hasURR = true
+			break
+		}
+	}
+	if hasURR {
+		go h.pollSessionUsage(ctx, clientAddr, seid)
+	}
 
-			// Create response IEs
-			responseIEs := []*ie.IE{
-				ie.NewNodeID("", "", "127.0.0.1"),
-				ie.NewCause(ie.CauseRequestAccepted),
-				msg.CPFSEID, // Echo back the F-SEID from request
-			}
+	h.nextSequence.Add(1)
+	return response, nil
+}
 
-			// Add all created PDRs
-			responseIEs = append(responseIEs, createdPDRs...)
-
-			// Create Session Establishment Response using correct API
-			// Format: NewSessionEstablishmentResponse(mp, fo, seid, seq, pri, ies...)
-			response := message.NewSessionEstablishmentResponse(
-				0, 0,                    // mp, fo flags
-				seid,                    // SEID
-				msg.SequenceNumber,      // Sequence number
-				0,                       // Priority
-				responseIEs...,          // All IEs
-			)
-
-			respData, err := response.Marshal()
-			if err != nil {
-				log.Printf("Failed to marshal Session Establishment Response: %v", err)
-				continue
-			}
+func (h *sessionHandler) HandleSessionModificationRequest(ctx context.Context, from net.Addr, rawMsg message.Message) (message.Message, error) {
+	msg, ok := rawMsg.(*message.SessionModificationRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T for Session Modification Request", rawMsg)
+	}
+	start := time.Now()
+	defer observeRequest(msg.MessageTypeName(), start)
+	messagesTotal.WithLabelValues(msg.MessageTypeName(), directionReceived, "").Inc()
 
-			_, err = conn.WriteToUDP(respData, clientAddr)
-			if err != nil {
-				log.Printf("Failed to send Session Establishment Response: %v", err)
-				continue
-			}
+	fmt.Printf("Processing Session Modification Request for session 0x%016x\n", msg.SEID())
 
-			fmt.Printf("Sent Session Establishment Response for session 0x%016x\n", seid)
-
-			// Simulate quota exhaustion after 2 seconds
-			go func(seid uint64, clientAddr *net.UDPAddr, seq uint32) {
-				time.Sleep(2 * time.Second)
-				fmt.Printf("  [QUOTA EXHAUSTED] Sending Session Report Request for session 0x%016x\n", seid)
-
-				// Create Session Report Request with usage report
-				reportIEs := []*ie.IE{
-					ie.NewReportType(0, 1, 0, 0), // USAR flag set
-					// Create a simplified usage report
-					ie.NewUsageReportWithinSessionReportRequest(
-						ie.NewURRID(1),                        // URR ID
-						ie.NewURSEQN(1),                       // UR Sequence Number  
-						ie.NewUsageReportTrigger(0, 1, 0, 0, 0, 0, 0, 0), // Volume threshold trigger
-					),
-				}
-
-				reportRequest := message.NewSessionReportRequest(
-					0, 0,        // mp, fo flags
-					seid,        // SEID
-					seq,         // Sequence
-					0,           // Priority
-					reportIEs..., // IEs
-				)
-
-				reportData, err := reportRequest.Marshal()
-				if err != nil {
-					log.Printf("Failed to marshal Session Report Request: %v", err)
-					return
-				}
-
-				_, err = conn.WriteToUDP(reportData, clientAddr)
-				if err != nil {
-					log.Printf("Failed to send Session Report Request: %v", err)
-				} else {
-					fmt.Printf("Sent Session Report Request for session 0x%016x\n", seid)
-				}
-			}(seid, clientAddr, nextSequence)
-
-			nextSequence++
-
-		case *message.SessionModificationRequest:
-			fmt.Printf("Processing Session Modification Request for session 0x%016x\n", msg.SEID())
-
-			response := message.NewSessionModificationResponse(
-				0, 0,                // mp, fo flags
-				msg.SEID(),          // SEID
-				msg.SequenceNumber,  // Sequence
-				0,                   // Priority
-				ie.NewCause(ie.CauseRequestAccepted), // Cause
-			)
-
-			respData, err := response.Marshal()
-			if err != nil {
-				log.Printf("Failed to marshal Session Modification Response: %v", err)
-				continue
-			}
+	if err := h.applyModifyRules(msg.SEID(), msg.IEs); err != nil {
+		return nil, fmt.Errorf("applying modify rules for session 0x%016x: %w", msg.SEID(), err)
+	}
 
-			_, err = conn.WriteToUDP(respData, clientAddr)
-			if err != nil {
-				log.Printf("Failed to send Session Modification Response: %v", err)
-			} else {
-				fmt.Println("Sent Session Modification Response")
-			}
+	response := message.NewSessionModificationResponse(
+		0, 0, // mp, fo flags
+		msg.SEID(),                           // SEID
+		msg.SequenceNumber,                   // Sequence
+		0,                                    // Priority
+		ie.NewCause(ie.CauseRequestAccepted), // Cause
+	)
+	fmt.Println("Sent Session Modification Response")
+	return response, nil
+}
 
-		case *message.SessionDeletionRequest:
-			fmt.Printf("Processing Session Deletion Request for session 0x%016x\n", msg.SEID())
-			seid := msg.SEID()
-
-			// Remove session from tracking
-			delete(sessions, seid)
-
-			response := message.NewSessionDeletionResponse(
-				0, 0,                // mp, fo flags
-				seid,                // SEID
-				msg.SequenceNumber,  // Sequence
-				0,                   // Priority
-				ie.NewCause(ie.CauseRequestAccepted), // Cause
-			)
-
-			respData, err := response.Marshal()
-			if err != nil {
-				log.Printf("Failed to marshal Session Deletion Response: %v", err)
-				continue
-			}
+func (h *sessionHandler) HandleSessionDeletionRequest(ctx context.Context, from net.Addr, rawMsg message.Message) (message.Message, error) {
+	msg, ok := rawMsg.(*message.SessionDeletionRequest)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T for Session Deletion Request", rawMsg)
+	}
+	start := time.Now()
+	defer observeRequest(msg.MessageTypeName(), start)
+	messagesTotal.WithLabelValues(msg.MessageTypeName(), directionReceived, "").Inc()
 
-			_, err = conn.WriteToUDP(respData, clientAddr)
-			if err != nil {
-				log.Printf("Failed to send Session Deletion Response: %v", err)
-			} else {
-				fmt.Printf("Sent Session Deletion Response for session 0x%016x\n", seid)
-			}
+	fmt.Printf("Processing Session Deletion Request for session 0x%016x\n", msg.SEID())
+	seid := msg.SEID()
 
-		case *message.SessionReportResponse:
-			fmt.Println("  Received Session Report Response - quota exhaustion acknowledged")
+	if err := h.dp.SessionDelete(seid); err != nil {
+		return nil, fmt.Errorf("deleting session 0x%016x: %w", seid, err)
+	}
 
-			// Check cause
-			if msg.Cause != nil {
-				fmt.Printf("  Response cause: %d\n", msg.Cause.Payload[0])
-			}
+	delete(h.sessions, seid)
+	sessionsActive.Set(float64(len(h.sessions)))
 
-		default:
-			fmt.Printf("Received unhandled message type: %s\n", msg.MessageTypeName())
-		}
+	response := message.NewSessionDeletionResponse(
+		0, 0, // mp, fo flags
+		seid,                                 // SEID
+		msg.SequenceNumber,                   // Sequence
+		0,                                    // Priority
+		ie.NewCause(ie.CauseRequestAccepted), // Cause
+	)
+	fmt.Printf("Sent Session Deletion Response for session 0x%016x\n", seid)
+	return response, nil
+}
+
+func main() {
+	var (
+		addr        = flag.String("addr", "127.0.0.1:8805", "Local address to listen on")
+		metricsAddr = flag.String("metrics-addr", "", "Address to serve Prometheus /metrics on (disabled if empty)")
+		logRules    = flag.Bool("log-rules", false, "Log every PDR/FAR/QER/URR datapath operation as JSON to stderr")
+	)
+	flag.Parse()
+
+	if *metricsAddr != "" {
+		go serveMetrics(*metricsAddr)
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to resolve address: %v", err)
+	}
+
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		log.Fatalf("Failed to listen on UDP: %v", err)
 	}
-}
\ No newline at end of file
+	defer conn.Close()
+
+	fmt.Printf("Fixed Go PFCP Server listening on %s\n", *addr)
+	fmt.Printf("Socket bound successfully to %s\n", conn.LocalAddr())
+
+	var dp datapath.Datapath = datapath.NewMockDatapath()
+	if *logRules {
+		dp = datapath.NewLoggingDatapath(dp, os.Stderr)
+	}
+
+	ch := pfcpchan.NewUDPChannel(conn)
+	tx := pfcptx.New(ch,
+		pfcptx.WithOnParseError(func(error) {
+			messagesTotal.WithLabelValues("unknown", directionReceived, causeParseError).Inc()
+		}),
+		pfcptx.WithOnResponseSent(func(resp message.Message) {
+			messagesTotal.WithLabelValues(resp.MessageTypeName(), directionSent, causeAccepted).Inc()
+		}),
+	)
+	handler := newSessionHandler(dp, tx)
+
+	tx.RegisterHandler(message.MsgTypeAssociationSetupRequest, handler.HandleAssociationSetupRequest)
+	tx.RegisterHandler(message.MsgTypeSessionEstablishmentRequest, handler.HandleSessionEstablishmentRequest)
+	tx.RegisterHandler(message.MsgTypeSessionModificationRequest, handler.HandleSessionModificationRequest)
+	tx.RegisterHandler(message.MsgTypeSessionDeletionRequest, handler.HandleSessionDeletionRequest)
+
+	if err := tx.Serve(context.Background()); err != nil {
+		log.Fatalf("Server loop exited: %v", err)
+	}
+}
@@ -1,9 +1,9 @@
 // Simplified Go PFCP Server for basic interoperability testing
 // This version focuses on basic message parsing and response generation
-
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -12,21 +12,88 @@ import (
 
 	"github.com/wmnsk/go-pfcp/ie"
 	"github.com/wmnsk/go-pfcp/message"
+
+	"github.com/xandlom/rs-pfcp/go-interop/pfcpchan"
 )
 
+// simpleHandler implements pfcpchan.Handler with the same stateless,
+// one-response-per-request behavior the original switch statement had -
+// unlike sessionHandler, it keeps no session bookkeeping.
+type simpleHandler struct {
+	pfcpchan.UnimplementedHandler
+}
+
+func (simpleHandler) HandleAssociationSetupRequest(ctx context.Context, ch pfcpchan.Channel, from net.Addr, msg *message.AssociationSetupRequest) error {
+	response := message.NewAssociationSetupResponse(
+		msg.SequenceNumber,
+		ie.NewNodeID("", "", "127.0.0.1"),
+		ie.NewCause(ie.CauseRequestAccepted),
+		ie.NewRecoveryTimeStamp(time.Now()),
+	)
+	return sendResponse(ctx, ch, from, msg.MessageTypeName(), response)
+}
+
+func (simpleHandler) HandleSessionEstablishmentRequest(ctx context.Context, ch pfcpchan.Channel, from net.Addr, msg *message.SessionEstablishmentRequest) error {
+	response := message.NewSessionEstablishmentResponse(
+		0, 0, // mp, fo flags
+		msg.SEID(),
+		msg.SequenceNumber,
+		0, // Priority
+		ie.NewCause(ie.CauseRequestAccepted),
+	)
+	return sendResponse(ctx, ch, from, msg.MessageTypeName(), response)
+}
+
+func (simpleHandler) HandleSessionModificationRequest(ctx context.Context, ch pfcpchan.Channel, from net.Addr, msg *message.SessionModificationRequest) error {
+	response := message.NewSessionModificationResponse(
+		0, 0, // mp, fo flags
+		msg.SEID(),
+		msg.SequenceNumber,
+		0, // Priority
+		ie.NewCause(ie.CauseRequestAccepted),
+	)
+	return sendResponse(ctx, ch, from, msg.MessageTypeName(), response)
+}
+
+func (simpleHandler) HandleSessionDeletionRequest(ctx context.Context, ch pfcpchan.Channel, from net.Addr, msg *message.SessionDeletionRequest) error {
+	response := message.NewSessionDeletionResponse(
+		0, 0, // mp, fo flags
+		msg.SEID(),
+		msg.SequenceNumber,
+		0, // Priority
+		ie.NewCause(ie.CauseRequestAccepted),
+	)
+	return sendResponse(ctx, ch, from, msg.MessageTypeName(), response)
+}
+
+func (simpleHandler) HandleSessionReportResponse(ctx context.Context, ch pfcpchan.Channel, from net.Addr, msg *message.SessionReportResponse) error {
+	fmt.Printf("Received Session Report Response - no response needed\n")
+	return nil
+}
+
+// sendResponse writes response to from, logging (rather than failing the
+// serve loop) on a write error, matching the original inline server's
+// continue-on-error behavior.
+func sendResponse(ctx context.Context, ch pfcpchan.Channel, from net.Addr, reqName string, response message.Message) error {
+	if err := ch.WriteMessage(ctx, from, response); err != nil {
+		log.Printf("Failed to send response to %s: %v", reqName, err)
+		return nil
+	}
+	fmt.Printf("Successfully parsed %s message from Rust client and sent response\n", reqName)
+	return nil
+}
+
 func main() {
 	var (
 		addr = flag.String("addr", "127.0.0.1:8805", "Local address to listen on")
 	)
 	flag.Parse()
 
-	// Parse the address
 	udpAddr, err := net.ResolveUDPAddr("udp", *addr)
 	if err != nil {
 		log.Fatalf("Failed to resolve address: %v", err)
 	}
 
-	// Create UDP listener
 	conn, err := net.ListenUDP("udp", udpAddr)
 	if err != nil {
 		log.Fatalf("Failed to listen on UDP: %v", err)
@@ -36,97 +103,8 @@ func main() {
 	fmt.Printf("Go PFCP Simple Server listening on %s\n", *addr)
 	fmt.Printf("Socket bound successfully to %s\n", conn.LocalAddr())
 
-	buf := make([]byte, 1500)
-
-	for {
-		n, clientAddr, err := conn.ReadFromUDP(buf)
-		if err != nil {
-			log.Printf("Failed to read UDP packet: %v", err)
-			continue
-		}
-
-		data := buf[:n]
-		fmt.Printf("Received %d bytes from %s\n", n, clientAddr)
-
-		// Try to parse as PFCP message
-		msg, err := message.Parse(data)
-		if err != nil {
-			log.Printf("Failed to parse PFCP message: %v", err)
-			continue
-		}
-
-		fmt.Printf("Parsed PFCP message: %s\n", msg.MessageTypeName())
-
-		// For basic compatibility testing, send appropriate responses
-		// based on the message type
-		var responseMsg message.Message
-
-		switch msg.MessageType() {
-		case message.MsgTypeAssociationSetupRequest:
-			// Send Association Setup Response
-			req := msg.(*message.AssociationSetupRequest)
-			responseMsg = message.NewAssociationSetupResponse(
-				req.SequenceNumber,
-				ie.NewNodeID("", "", "127.0.0.1"),
-				ie.NewCause(ie.CauseRequestAccepted),
-				ie.NewRecoveryTimeStamp(time.Now()),
-			)
-		case message.MsgTypeSessionEstablishmentRequest:
-			// Send Session Establishment Response
-			req := msg.(*message.SessionEstablishmentRequest)
-			responseMsg = message.NewSessionEstablishmentResponse(
-				0, // MP flag
-				0, // FO flag
-				req.SEID(),
-				req.SequenceNumber,
-				0, // Priority
-				ie.NewCause(ie.CauseRequestAccepted),
-			)
-		case message.MsgTypeSessionModificationRequest:
-			// Send Session Modification Response
-			req := msg.(*message.SessionModificationRequest)
-			responseMsg = message.NewSessionModificationResponse(
-				0, // MP flag
-				0, // FO flag
-				req.SEID(),
-				req.SequenceNumber,
-				0, // Priority
-				ie.NewCause(ie.CauseRequestAccepted),
-			)
-		case message.MsgTypeSessionDeletionRequest:
-			// Send Session Deletion Response
-			req := msg.(*message.SessionDeletionRequest)
-			responseMsg = message.NewSessionDeletionResponse(
-				0, // MP flag
-				0, // FO flag
-				req.SEID(),
-				req.SequenceNumber,
-				0, // Priority
-				ie.NewCause(ie.CauseRequestAccepted),
-			)
-		case message.MsgTypeSessionReportResponse:
-			// Client sent a Session Report Response - no need to respond
-			fmt.Printf("Received Session Report Response - no response needed\n")
-			continue
-		default:
-			fmt.Printf("No response handler for message type: %s\n", msg.MessageTypeName())
-			continue
-		}
-
-		// Marshal and send response
-		if responseMsg != nil {
-			responseBytes := make([]byte, responseMsg.MarshalLen())
-			if err := responseMsg.MarshalTo(responseBytes); err != nil {
-				log.Printf("Failed to marshal response: %v", err)
-				continue
-			}
-
-			if _, err := conn.WriteToUDP(responseBytes, clientAddr); err != nil {
-				log.Printf("Failed to send response: %v", err)
-				continue
-			}
-
-			fmt.Printf("Successfully parsed %s message from Rust client and sent response\n", msg.MessageTypeName())
-		}
+	ch := pfcpchan.NewUDPChannel(conn)
+	if err := pfcpchan.Serve(context.Background(), ch, simpleHandler{}); err != nil {
+		log.Fatalf("Server loop exited: %v", err)
 	}
-}
\ No newline at end of file
+}